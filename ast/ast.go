@@ -10,6 +10,7 @@ import (
 type Node interface {
 	TokenLiteral() string //returns the literal value of the token its associated with
 	String() string       //for debugging and comparison
+	Pos() token.Position  //position (in the source code) of the token its associated with
 }
 
 // Statements, a type of ndoe in our AST
@@ -29,6 +30,7 @@ type Expression interface {
 // Root node of AST
 type Program struct {
 	Statements []Statement
+	Resolved   bool // set by resolver.Resolve once every identifier knows where its variable lives
 }
 
 func (p *Program) TokenLiteral() string {
@@ -40,6 +42,15 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	// The program starts wherever its first statement does
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	// Create a buffer
 	var out bytes.Buffer
@@ -53,13 +64,15 @@ func (p *Program) String() string {
 
 // Implements Statement and Node interface
 type LetStatement struct {
-	Token token.Token // the token.LET token
+	Token token.Token // the token.LET token (token.CONST for const x = 5)
 	Name  *Identifier //identifier for the binding (ex: x in let x = 5)
 	Value Expression  //expression that produces the value (the 5 in let x = 5)
+	Const bool        // const x = 5, x can't be reassigned
 }
 
 func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
+func (ls *LetStatement) Pos() token.Position  { return ls.Token.Pos }
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
@@ -88,12 +101,33 @@ note:
 
 // ex: the x in let x = 5
 type Identifier struct {
-	Token token.Token // the token.IDENT token
-	Value string
+	Token   token.Token // the token.IDENT token
+	Value   string
+	Binding Binding // where the variable lives, filled in by the resolver
+}
+
+type BindingKind int
+
+const (
+	UNRESOLVED BindingKind = iota // the resolver hasn't seen it, or it isn't a variable (property names, named arguments)
+	GLOBAL                        // looked up by name in the global environment
+	LOCAL                         // Slot of the scope Depth levels up from the current one
+)
+
+/**
+Where the variable an identifier refers to lives, see the resolver package.
+
+Functions, blocks and loops have local scopes, everything at the top level (outside of those) is a global.
+**/
+type Binding struct {
+	Kind  BindingKind
+	Depth int
+	Slot  int
 }
 
 func (i *Identifier) expressionNode()      {}
 func (i *Identifier) TokenLiteral() string { return i.Token.Literal }
+func (i *Identifier) Pos() token.Position  { return i.Token.Pos }
 func (i *Identifier) String() string       { return i.Value }
 
 type ReturnStatement struct {
@@ -103,6 +137,7 @@ type ReturnStatement struct {
 
 func (rs *ReturnStatement) statementNode()       {}
 func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
+func (rs *ReturnStatement) Pos() token.Position  { return rs.Token.Pos }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
@@ -127,6 +162,7 @@ type ExpressionStatement struct {
 
 func (es *ExpressionStatement) statementNode()       {}
 func (es *ExpressionStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExpressionStatement) Pos() token.Position  { return es.Token.Pos }
 func (es *ExpressionStatement) String() string {
 	if es.Expression != nil {
 		return es.Expression.String()
@@ -142,8 +178,19 @@ type IntegerLiteral struct {
 
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
+func (il *IntegerLiteral) Pos() token.Position  { return il.Token.Pos }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) Pos() token.Position  { return fl.Token.Pos }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
 type PrefixExpression struct {
 	Token    token.Token //the prefix token: !, -
 	Operator string      // !, -
@@ -152,6 +199,7 @@ type PrefixExpression struct {
 
 func (pe *PrefixExpression) expressionNode()      {}
 func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) Pos() token.Position  { return pe.Token.Pos }
 func (pe *PrefixExpression) String() string {
 	var out bytes.Buffer
 	/*
@@ -160,7 +208,10 @@ func (pe *PrefixExpression) String() string {
 	*/
 	out.WriteString("(")
 	out.WriteString(pe.Operator)
-	out.WriteString(pe.Right.String())
+	// missing when the operand couldn't be parsed: "!" on its own
+	if pe.Right != nil {
+		out.WriteString(pe.Right.String())
+	}
 	out.WriteString(")")
 
 	return out.String()
@@ -175,6 +226,7 @@ type InfixExpression struct {
 
 func (ie *InfixExpression) expressionNode()      {}
 func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -186,6 +238,33 @@ func (ie *InfixExpression) String() string {
 	return out.String()
 }
 
+/**
+&& and ||
+
+Separate from InfixExpression since they short-circuit:
+the right side is only evaluated when the left side doesn't already decide the result.
+**/
+type LogicalExpression struct {
+	Token    token.Token // the operator token: &&, ||
+	Left     Expression
+	Operator string
+	Right    Expression
+}
+
+func (le *LogicalExpression) expressionNode()      {}
+func (le *LogicalExpression) TokenLiteral() string { return le.Token.Literal }
+func (le *LogicalExpression) Pos() token.Position  { return le.Token.Pos }
+func (le *LogicalExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(le.Left.String())
+	out.WriteString(" " + le.Operator + " ")
+	out.WriteString(le.Right.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
@@ -193,6 +272,7 @@ type Boolean struct {
 
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
+func (b *Boolean) Pos() token.Position  { return b.Token.Pos }
 func (b *Boolean) String() string       { return b.Token.Literal }
 
 // if (condition) <consequence> else <alternative>
@@ -205,6 +285,7 @@ type IfExpression struct {
 
 func (ie *IfExpression) expressionNode()      {}
 func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -221,13 +302,139 @@ func (ie *IfExpression) String() string {
 	return out.String()
 }
 
+/**
+match (subject) { <arm>, <arm>, ... }
+
+- The arms are tried in order, the first one that matches is evaluated.
+- An arm matches when one of its patterns equals the subject and its guard (if any) is truthy.
+**/
+type MatchExpression struct {
+	Token   token.Token // the 'match' token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) Pos() token.Position  { return me.Token.Pos }
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match(")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") {")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// <pattern>, <pattern> if <guard> => <body>
+type MatchArm struct {
+	Token    token.Token  // the first token of the first pattern
+	Patterns []Expression // literals or the _ wildcard
+	Guard    Expression   // optional
+	Body     *BlockStatement
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	patterns := []string{}
+	for _, p := range ma.Patterns {
+		patterns = append(patterns, p.String())
+	}
+
+	out.WriteString(strings.Join(patterns, ", "))
+
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+// Reports whether the pattern is the catch-all _ pattern
+func IsWildcard(pattern Expression) bool {
+	ident, ok := pattern.(*Identifier)
+	return ok && ident.Value == "_"
+}
+
+/**
+try { ... } catch (e) { ... } finally { ... }
+
+- The catch parameter is optional: catch { ... }
+- Either the catch or the finally block can be left out, not both.
+**/
+type TryStatement struct {
+	Token       token.Token // the 'try' token
+	Block       *BlockStatement
+	CatchParam  *Identifier // nil if the catch block doesn't name the error
+	Catch       *BlockStatement
+	Finally     *BlockStatement
+	CatchLocals int // number of local variables of the catch block (the parameter included), set by the resolver
+}
+
+func (ts *TryStatement) statementNode()       {}
+func (ts *TryStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *TryStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *TryStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try {")
+	out.WriteString(ts.Block.String())
+	out.WriteString("}")
+
+	if ts.Catch != nil {
+		out.WriteString(" catch")
+		if ts.CatchParam != nil {
+			out.WriteString("(" + ts.CatchParam.String() + ")")
+		}
+		out.WriteString(" {")
+		out.WriteString(ts.Catch.String())
+		out.WriteString("}")
+	}
+
+	if ts.Finally != nil {
+		out.WriteString(" finally {")
+		out.WriteString(ts.Finally.String())
+		out.WriteString("}")
+	}
+
+	return out.String()
+}
+
+// throw <expression>;
+type ThrowStatement struct {
+	Token token.Token // the 'throw' token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode()       {}
+func (ts *ThrowStatement) TokenLiteral() string { return ts.Token.Literal }
+func (ts *ThrowStatement) Pos() token.Position  { return ts.Token.Pos }
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
+
 type BlockStatement struct {
 	Token      token.Token
 	Statements []Statement
+	Locals     int // number of variables declared in the block, set by the resolver. 0 if it doesn't get a scope of its own
 }
 
 func (bs *BlockStatement) statementNode()       {}
 func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BlockStatement) Pos() token.Position  { return bs.Token.Pos }
 func (bs *BlockStatement) String() string {
 	var out bytes.Buffer
 
@@ -257,23 +464,28 @@ outerFunc(a, b, fn(c, d) { return a > b; });
 **/
 type FunctionLiteral struct {
 	Token      token.Token
-	Parameters []*Identifier   // (x,y,z)
-	Body       *BlockStatement // { x + y; }, { foo > bar; }
+	Name       string                // empty for anonymous functions
+	Parameters []*Identifier         // (x,y,z)
+	Defaults   map[string]Expression // (x, y = 10) => {"y": 10}
+	Rest       *Identifier           // (x, ...rest), nil if there's no rest parameter
+	Body       *BlockStatement       // { x + y; }, { foo > bar; }
+	Locals     int                   // number of local variables (parameters included), set by the resolver
 }
 
 func (fl *FunctionLiteral) expressionNode()      {}
 func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) Pos() token.Position  { return fl.Token.Pos }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	// fn(x,y,z)
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
-	}
+	params := ParameterStrings(fl.Parameters, fl.Defaults, fl.Rest)
 
-	//fn(params)
+	//fn(params), fn name(params)
 	out.WriteString(fl.TokenLiteral())
+	if fl.Name != "" {
+		out.WriteString(" ")
+		out.WriteString(fl.Name)
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
@@ -283,6 +495,43 @@ func (fl *FunctionLiteral) String() string {
 
 }
 
+// x, y = 10, ...rest
+func ParameterStrings(params []*Identifier, defaults map[string]Expression, rest *Identifier) []string {
+	out := []string{}
+
+	for _, p := range params {
+		if value, ok := defaults[p.Value]; ok {
+			out = append(out, p.String()+" = "+value.String())
+			continue
+		}
+
+		out = append(out, p.String())
+	}
+
+	if rest != nil {
+		out = append(out, "..."+rest.String())
+	}
+
+	return out
+}
+
+/**
+fn name(x, y) { ... }
+
+A named function declaration. Declarations are hoisted to the top of the block they're in,
+so they can be called before they're declared and can call each other (mutual recursion).
+**/
+type FunctionStatement struct {
+	Token    token.Token // the 'fn' token
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (fs *FunctionStatement) statementNode()       {}
+func (fs *FunctionStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *FunctionStatement) Pos() token.Position  { return fs.Token.Pos }
+func (fs *FunctionStatement) String() string       { return fs.Function.String() }
+
 /**
 Call expression
 <expression>(<comma separated expressions>)
@@ -295,13 +544,15 @@ example: add(1,2) => fn(a,b) { a + b; }(1,2)
 **/
 
 type CallExpression struct {
-	Token     token.Token // the '(' token
-	Function  Expression  // idenfifier or function literal
-	Arguments []Expression
+	Token          token.Token // the '(' token
+	Function       Expression  // idenfifier or function literal
+	Arguments      []Expression
+	NamedArguments []*NamedArgument // f(1, y: 2) => [y: 2], always after the positional ones
 }
 
 func (ce *CallExpression) expressionNode()      {}
 func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) Pos() token.Position  { return ce.Token.Pos }
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -311,6 +562,10 @@ func (ce *CallExpression) String() string {
 		args = append(args, a.String())
 	}
 
+	for _, a := range ce.NamedArguments {
+		args = append(args, a.String())
+	}
+
 	out.WriteString(ce.Function.String())
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
@@ -319,6 +574,15 @@ func (ce *CallExpression) String() string {
 	return out.String()
 }
 
+// name: value, only valid inside of a call's argument list
+type NamedArgument struct {
+	Token token.Token // the name
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) String() string { return na.Name.String() + ": " + na.Value.String() }
+
 type StringLiteral struct {
 	Token token.Token
 	Value string
@@ -326,8 +590,42 @@ type StringLiteral struct {
 
 func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) Pos() token.Position  { return sl.Token.Pos }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+/**
+"Hello ${name}, you have ${len(items)} items"
+
+Parts alternate between *StringLiteral text and embedded expressions,
+starting and ending with text (which can be empty): "Hello ", name, ", you have ", len(items), " items"
+**/
+type InterpolatedString struct {
+	Token token.Token // the token.INTERP_START token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) Pos() token.Position  { return is.Token.Pos }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("\"")
+	for i, part := range is.Parts {
+		if i%2 == 0 {
+			out.WriteString(part.String())
+			continue
+		}
+
+		out.WriteString("${")
+		out.WriteString(part.String())
+		out.WriteString("}")
+	}
+	out.WriteString("\"")
+
+	return out.String()
+}
+
 type ArrayLiteral struct {
 	Token    token.Token // the '[' token
 	Elements []Expression
@@ -335,6 +633,7 @@ type ArrayLiteral struct {
 
 func (al *ArrayLiteral) expressionNode()      {}
 func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) Pos() token.Position  { return al.Token.Pos }
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
@@ -359,6 +658,7 @@ type IndexExpression struct {
 
 func (ie *IndexExpression) expressionNode()      {}
 func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) Pos() token.Position  { return ie.Token.Pos }
 func (ie *IndexExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -378,6 +678,7 @@ type IndexAssignment struct {
 
 func (ia *IndexAssignment) expressionNode()      {}
 func (ia *IndexAssignment) TokenLiteral() string { return ia.Token.Literal }
+func (ia *IndexAssignment) Pos() token.Position  { return ia.Token.Pos }
 func (ia *IndexAssignment) String() string {
 	var out bytes.Buffer
 	out.WriteString(ia.Left.String())
@@ -392,21 +693,29 @@ func (ia *IndexAssignment) String() string {
 /**
 The basic syntactic structure of a hash literal is:
 {<expression> : <expression>, ... }
+
+The pairs are in the order they're written in, that's the order the hash gets its keys in.
 **/
 type HashLiteral struct {
 	Token token.Token // the '{' token
-	Pairs map[Expression]Expression
+	Pairs []HashLiteralPair
+}
+
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) expressionNode()      {}
 func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) Pos() token.Position  { return hl.Token.Pos }
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
 
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+":"+pair.Value.String())
 	}
 	// { <expression> : <expression>, ...}
 	out.WriteString("{")
@@ -418,13 +727,14 @@ func (hl *HashLiteral) String() string {
 
 type InternalFunctionCall struct {
 	Token              token.Token  // the '.' token
-	CallerIdentifier   *Identifier  //someArray, someHash, etc
+	Caller             Expression   //someArray, someHash, [1, 2, 3], person["tags"], arr.map(f), etc
 	FunctionIdentifier *Identifier  // pop, delete, etc.
 	Arguments          []Expression //(1,2,3), (), etc.
 }
 
 func (ifc *InternalFunctionCall) expressionNode()      {}
 func (ifc *InternalFunctionCall) TokenLiteral() string { return ifc.Token.Literal }
+func (ifc *InternalFunctionCall) Pos() token.Position  { return ifc.Token.Pos }
 func (ifc *InternalFunctionCall) String() string {
 	var out bytes.Buffer
 
@@ -434,7 +744,7 @@ func (ifc *InternalFunctionCall) String() string {
 		args = append(args, a.String())
 	}
 
-	out.WriteString(ifc.CallerIdentifier.String())   //someArray, someHash, etc
+	out.WriteString(ifc.Caller.String())             //someArray, someHash, etc
 	out.WriteString(ifc.Token.Literal)               // .
 	out.WriteString(ifc.FunctionIdentifier.String()) // delete, pop
 	out.WriteString("(")
@@ -444,6 +754,26 @@ func (ifc *InternalFunctionCall) String() string {
 	return out.String()
 }
 
+// person.name, same as person["name"]
+type PropertyExpression struct {
+	Token    token.Token // the '.' token
+	Left     Expression
+	Property *Identifier
+}
+
+func (pe *PropertyExpression) expressionNode()      {}
+func (pe *PropertyExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PropertyExpression) Pos() token.Position  { return pe.Token.Pos }
+func (pe *PropertyExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(pe.Left.String())
+	out.WriteString(".")
+	out.WriteString(pe.Property.String())
+	out.WriteString(")")
+	return out.String()
+}
+
 type AssignmentExpression struct {
 	Token token.Token // the = token
 	Name  *Identifier //identifier for the binding (ex: x in x = 5)
@@ -452,6 +782,7 @@ type AssignmentExpression struct {
 
 func (as *AssignmentExpression) expressionNode()      {}
 func (as *AssignmentExpression) TokenLiteral() string { return as.Token.Literal }
+func (as *AssignmentExpression) Pos() token.Position  { return as.Token.Pos }
 func (as *AssignmentExpression) String() string {
 	var out bytes.Buffer
 
@@ -469,16 +800,99 @@ func (as *AssignmentExpression) String() string {
 }
 
 // for (<counter variable init>;<loop conditional>;<counterVar increment>) { <statements> };
+// while (x < 10) { x = x + 1 }
+type WhileStatement struct {
+	Token     token.Token // the 'while' token
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode()       {}
+func (ws *WhileStatement) TokenLiteral() string { return ws.Token.Literal }
+func (ws *WhileStatement) Pos() token.Position  { return ws.Token.Pos }
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString("while")
+	out.WriteString("(")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(")")
+	out.WriteString("{")
+	out.WriteString(ws.Body.String())
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// break; stops the loop it's in
+type BreakStatement struct {
+	Token token.Token // the 'break' token
+}
+
+func (bs *BreakStatement) statementNode()       {}
+func (bs *BreakStatement) TokenLiteral() string { return bs.Token.Literal }
+func (bs *BreakStatement) Pos() token.Position  { return bs.Token.Pos }
+func (bs *BreakStatement) String() string       { return bs.Token.Literal + ";" }
+
+// continue; skips to the next iteration of the loop it's in
+type ContinueStatement struct {
+	Token token.Token // the 'continue' token
+}
+
+func (cs *ContinueStatement) statementNode()       {}
+func (cs *ContinueStatement) TokenLiteral() string { return cs.Token.Literal }
+func (cs *ContinueStatement) Pos() token.Position  { return cs.Token.Pos }
+func (cs *ContinueStatement) String() string       { return cs.Token.Literal + ";" }
+
+/**
+for (x in arr) { ... }
+for (k, v in hash) { ... }
+
+Variables has one or two identifiers (see object.Iterable for what they get bound to)
+**/
+type ForInStatement struct {
+	Token     token.Token // the 'for' token
+	Variables []*Identifier
+	Iterable  Expression
+	Body      *BlockStatement
+	Locals    int // number of local variables of an iteration (the loop variables included), set by the resolver
+}
+
+func (fi *ForInStatement) statementNode()       {}
+func (fi *ForInStatement) TokenLiteral() string { return fi.Token.Literal }
+func (fi *ForInStatement) Pos() token.Position  { return fi.Token.Pos }
+func (fi *ForInStatement) String() string {
+	var out bytes.Buffer
+
+	variables := []string{}
+	for _, v := range fi.Variables {
+		variables = append(variables, v.String())
+	}
+
+	out.WriteString("for")
+	out.WriteString("(")
+	out.WriteString(strings.Join(variables, ", "))
+	out.WriteString(" in ")
+	out.WriteString(fi.Iterable.String())
+	out.WriteString(")")
+	out.WriteString("{")
+	out.WriteString(fi.Body.String())
+	out.WriteString("}")
+
+	return out.String()
+}
+
 type ForLoopStatement struct {
 	Token         token.Token   // the 'for' token
 	CounterVar    *LetStatement //identifier for the binding (ex: x in x = 5)
 	LoopCondition Expression
 	CounterUpdate *AssignmentExpression //expression that produces the value (the 5 in let x = 5)
 	LoopBlock     *BlockStatement
+	Locals        int // number of local variables of an iteration (the counter), set by the resolver
 }
 
 func (fl *ForLoopStatement) statementNode()       {}
 func (fl *ForLoopStatement) TokenLiteral() string { return fl.Token.Literal }
+func (fl *ForLoopStatement) Pos() token.Position  { return fl.Token.Pos }
 func (fl *ForLoopStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(fl.TokenLiteral())
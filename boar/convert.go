@@ -0,0 +1,406 @@
+package boar
+
+import (
+	"boar/evaluator"
+	"boar/object"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+/**
+Conversions between Go and boar values.
+
+Go => boar (toObject):
+- nil => null, bool => boolean, string => string
+- any int / uint type => integer, float32 / float64 => float
+- slices and arrays => array, maps => hash (keys must be usable as hash keys).
+Go maps have no order, the keys are sorted so the hash is the same every time: by type, then by value, see sortPairs
+- functions => builtin functions, see wrapFunction
+- pointers / interfaces => whatever they point to
+- object.Object values are passed along as they are
+
+boar => Go (fromObject):
+- null => nil, integer => int64, float => float64, string => string, boolean => bool
+- array => []interface{}
+- hash => map[string]interface{} when every key is a string, map[interface{}]interface{} otherwise
+- anything else (functions, ranges) => the object.Object itself,
+so is an array or hash found inside of itself (a[0] = a), converting it again would never end
+**/
+
+var (
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+)
+
+func toObject(value interface{}) (object.Object, error) {
+	return toNamedObject("go function", value)
+}
+
+// name is only used for functions, it shows up in their error messages
+func toNamedObject(name string, value interface{}) (object.Object, error) {
+	switch value := value.(type) {
+	case nil:
+		return evaluator.NULL, nil
+	case object.Object:
+		return value, nil
+	case bool:
+		return toBoolean(value), nil
+	case string:
+		return &object.String{Value: value}, nil
+	}
+
+	v := reflect.ValueOf(value)
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return toBoolean(v.Bool()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d doesn't fit in an integer", v.Uint())
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+
+	case reflect.Slice, reflect.Array:
+		elements := make([]object.Object, v.Len())
+		for idx := range elements {
+			element, err := toObject(v.Index(idx).Interface())
+			if err != nil {
+				return nil, err
+			}
+			elements[idx] = element
+		}
+		return &object.Array{Elements: elements}, nil
+
+	case reflect.Map:
+		pairs := []object.HashPair{}
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := toObject(iter.Key().Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := key.(object.Hashable); !ok {
+				return nil, fmt.Errorf("unusable value as hash key: %s", key.Type())
+			}
+
+			value, err := toObject(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+
+			pairs = append(pairs, object.HashPair{Key: key, Value: value})
+		}
+
+		sortPairs(pairs)
+
+		hash := &object.Hash{}
+		for _, pair := range pairs {
+			hash.Set(pair.Key.(object.Hashable).HashKey(), pair)
+		}
+		return hash, nil
+
+	case reflect.Func:
+		return wrapFunction(name, v)
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return evaluator.NULL, nil
+		}
+		return toNamedObject(name, v.Elem().Interface())
+	}
+
+	return nil, fmt.Errorf("unsupported Go type %T", value)
+}
+
+// Booleans, then integers, then strings (the order of their type names). Integers by value, the rest by Inspect()
+func sortPairs(pairs []object.HashPair) {
+	sort.Slice(pairs, func(i, j int) bool {
+		a, b := pairs[i].Key, pairs[j].Key
+
+		if a.Type() != b.Type() {
+			return a.Type() < b.Type()
+		}
+
+		if x, ok := a.(*object.Integer); ok {
+			return x.Value < b.(*object.Integer).Value
+		}
+
+		return a.Inspect() < b.Inspect()
+	})
+}
+
+func toBoolean(value bool) *object.Boolean {
+	if value {
+		return evaluator.TRUE
+	}
+	return evaluator.FALSE
+}
+
+func fromObject(obj object.Object) interface{} {
+	return convertObject(obj, map[object.Object]bool{})
+}
+
+// converting holds the arrays and hashes obj is nested in
+func convertObject(obj object.Object, converting map[object.Object]bool) interface{} {
+	switch obj.(type) {
+	case *object.Array, *object.Hash:
+		if converting[obj] {
+			return obj
+		}
+		converting[obj] = true
+		defer delete(converting, obj)
+	}
+
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil
+	case *object.Integer:
+		return obj.Value
+	case *object.Float:
+		return obj.Value
+	case *object.String:
+		return obj.Value
+	case *object.Boolean:
+		return obj.Value
+	case *object.Array:
+		elements := make([]interface{}, len(obj.Elements))
+		for idx, element := range obj.Elements {
+			elements[idx] = convertObject(element, converting)
+		}
+		return elements
+	case *object.Hash:
+		return fromHash(obj, converting)
+	}
+
+	return obj
+}
+
+func fromHash(hash *object.Hash, converting map[object.Object]bool) interface{} {
+	stringKeys := make(map[string]interface{}, hash.Len())
+	anyKeys := make(map[interface{}]interface{}, hash.Len())
+	onlyStrings := true
+
+	for _, pair := range hash.Pairs() {
+		key, value := convertObject(pair.Key, converting), convertObject(pair.Value, converting)
+
+		if str, ok := key.(string); ok {
+			stringKeys[str] = value
+		} else {
+			onlyStrings = false
+		}
+		anyKeys[key] = value
+	}
+
+	if onlyStrings {
+		return stringKeys
+	}
+
+	return anyKeys
+}
+
+/**
+Converts a boar value to the Go type t, for the arguments of registered functions.
+interface{} parameters get whatever fromObject gives back, object.Object parameters get the value as it is.
+**/
+func toGoValue(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if t == objectType {
+		return reflect.ValueOf(&obj).Elem(), nil
+	}
+
+	v := reflect.New(t).Elem()
+	mismatch := fmt.Errorf("expected %s, got %s", t, obj.Type())
+
+	switch t.Kind() {
+	case reflect.Interface:
+		value := fromObject(obj)
+		if value == nil {
+			return v, nil
+		}
+		if !reflect.TypeOf(value).AssignableTo(t) {
+			return v, mismatch
+		}
+		v.Set(reflect.ValueOf(value))
+
+	case reflect.Bool:
+		boolean, ok := obj.(*object.Boolean)
+		if !ok {
+			return v, mismatch
+		}
+		v.SetBool(boolean.Value)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		integer, ok := obj.(*object.Integer)
+		if !ok {
+			return v, mismatch
+		}
+		if v.OverflowInt(integer.Value) {
+			return v, fmt.Errorf("%d doesn't fit in %s", integer.Value, t)
+		}
+		v.SetInt(integer.Value)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		integer, ok := obj.(*object.Integer)
+		if !ok {
+			return v, mismatch
+		}
+		if integer.Value < 0 || v.OverflowUint(uint64(integer.Value)) {
+			return v, fmt.Errorf("%d doesn't fit in %s", integer.Value, t)
+		}
+		v.SetUint(uint64(integer.Value))
+
+	// integers are fine where a float is expected: sqrt(2)
+	case reflect.Float32, reflect.Float64:
+		switch number := obj.(type) {
+		case *object.Float:
+			v.SetFloat(number.Value)
+		case *object.Integer:
+			v.SetFloat(float64(number.Value))
+		default:
+			return v, mismatch
+		}
+
+	case reflect.String:
+		str, ok := obj.(*object.String)
+		if !ok {
+			return v, mismatch
+		}
+		v.SetString(str.Value)
+
+	case reflect.Slice:
+		arr, ok := obj.(*object.Array)
+		if !ok {
+			return v, mismatch
+		}
+		v.Set(reflect.MakeSlice(t, len(arr.Elements), len(arr.Elements)))
+		for idx, element := range arr.Elements {
+			converted, err := toGoValue(element, t.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.Index(idx).Set(converted)
+		}
+
+	case reflect.Map:
+		hash, ok := obj.(*object.Hash)
+		if !ok {
+			return v, mismatch
+		}
+		v.Set(reflect.MakeMapWithSize(t, hash.Len()))
+		for _, pair := range hash.Pairs() {
+			key, err := toGoValue(pair.Key, t.Key())
+			if err != nil {
+				return v, err
+			}
+			value, err := toGoValue(pair.Value, t.Elem())
+			if err != nil {
+				return v, err
+			}
+			v.SetMapIndex(key, value)
+		}
+
+	default:
+		return v, fmt.Errorf("unsupported parameter type %s", t)
+	}
+
+	return v, nil
+}
+
+/**
+Wraps a Go function in a builtin, see Interpreter.RegisterFunc
+
+The result types get checked right away (nothing, a value, an error or a value and an error),
+the arguments when the function gets called.
+**/
+func wrapFunction(name string, fn reflect.Value) (*object.Builtin, error) {
+	fnType := fn.Type()
+	numOut := fnType.NumOut()
+	returnsError := numOut > 0 && fnType.Out(numOut-1) == errorType
+
+	values := numOut
+	if returnsError {
+		values--
+	}
+
+	if values > 1 {
+		return nil, fmt.Errorf("%s returns %d values, functions can return a value, an error or a value and an error", name, numOut)
+	}
+
+	builtin := func(ctx *object.Context, args ...object.Object) (result object.Object) {
+		in, err := functionArguments(name, fnType, args)
+		if err != nil {
+			return err
+		}
+
+		// a panicking Go function is the host's problem, not a bug in the interpreter
+		defer func() {
+			if r := recover(); r != nil {
+				result = &object.Error{Kind: object.RUNTIME_ERROR, Message: fmt.Sprintf("%s panicked: %v", name, r)}
+			}
+		}()
+
+		out := fn.Call(in)
+
+		if returnsError && !out[numOut-1].IsNil() {
+			return &object.Error{Kind: object.RUNTIME_ERROR, Message: out[numOut-1].Interface().(error).Error()}
+		}
+
+		if values == 0 {
+			return evaluator.NULL
+		}
+
+		obj, convErr := toObject(out[0].Interface())
+		if convErr != nil {
+			return &object.Error{Kind: object.TYPE_ERROR, Message: fmt.Sprintf("result of %s: %s", name, convErr)}
+		}
+
+		return obj
+	}
+
+	return &object.Builtin{Fn: builtin}, nil
+}
+
+// The arguments of a call from boar converted to the parameter types of fnType
+func functionArguments(name string, fnType reflect.Type, args []object.Object) ([]reflect.Value, *object.Error) {
+	params := fnType.NumIn()
+
+	if fnType.IsVariadic() {
+		if len(args) < params-1 {
+			return nil, &object.Error{Kind: object.ARGUMENT_ERROR, Message: fmt.Sprintf("wrong number of arguments passed to %s. Got %d wanted at least %d", name, len(args), params-1)}
+		}
+	} else if len(args) != params {
+		return nil, &object.Error{Kind: object.ARGUMENT_ERROR, Message: fmt.Sprintf("wrong number of arguments passed to %s. Got %d wanted %d", name, len(args), params)}
+	}
+
+	in := make([]reflect.Value, len(args))
+
+	for idx, arg := range args {
+		var paramType reflect.Type
+		// the extra arguments of fn(format string, a ...interface{}) go into a
+		if fnType.IsVariadic() && idx >= params-1 {
+			paramType = fnType.In(params - 1).Elem()
+		} else {
+			paramType = fnType.In(idx)
+		}
+
+		value, err := toGoValue(arg, paramType)
+		if err != nil {
+			return nil, &object.Error{Kind: object.TYPE_ERROR, Message: fmt.Sprintf("argument %d to %s: %s", idx+1, name, err)}
+		}
+		in[idx] = value
+	}
+
+	return in, nil
+}
@@ -0,0 +1,231 @@
+/**
+Package boar runs boar code from Go programs:
+
+	interp := boar.New(boar.WithStdout(&out))
+	interp.RegisterFunc("env", os.Getenv)
+	interp.Set("retries", 3)
+
+	config, err := interp.Eval(`{"host": env("HOST"), "retries": retries * 2}`)
+	// config => map[string]interface{}{"host": "...", "retries": int64(6)}
+
+Values going in and out are converted between Go and boar automatically, see convert.go
+**/
+package boar
+
+import (
+	"boar/diagnostic"
+	"boar/evaluator"
+	"boar/lexer"
+	"boar/object"
+	"boar/parser"
+	"boar/resolver"
+	"boar/setuphelpers"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+/**
+An Interpreter holds the global environment of a program,
+every Eval / EvalFile / Call runs in it, so definitions stick around between them (like in the REPL).
+
+An Interpreter isn't safe for concurrent use, use one per goroutine.
+**/
+type Interpreter struct {
+	env *object.Environment
+}
+
+type config struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Option configures an Interpreter, see New
+type Option func(*config)
+
+// Where puts() writes to, os.Stdout by default
+func WithStdout(w io.Writer) Option {
+	return func(c *config) { c.stdout = w }
+}
+
+// The program's stderr, os.Stderr by default
+func WithStderr(w io.Writer) Option {
+	return func(c *config) { c.stderr = w }
+}
+
+// Where gets() reads from, os.Stdin by default
+func WithStdin(r io.Reader) Option {
+	return func(c *config) { c.stdin = r }
+}
+
+// A new interpreter with the builtin functions loaded
+func New(opts ...Option) *Interpreter {
+	c := &config{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	env := object.NewEnvironment()
+	env.SetContext(object.NewContext(c.stdin, c.stdout, c.stderr))
+	setuphelpers.LoadBuiltInMethods(env)
+
+	return &Interpreter{env: env}
+}
+
+/**
+Runs src and gives back the value of its last statement converted to Go (nil for statements like let).
+The error is a *SyntaxError if src doesn't parse or uses undefined variables, a *RuntimeError if it fails while running.
+**/
+func (i *Interpreter) Eval(src string) (interface{}, error) {
+	return i.run(lexer.New(src))
+}
+
+// Same as Eval for the contents of a file, errors point at the file
+func (i *Interpreter) EvalFile(path string) (interface{}, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.run(lexer.NewWithFile(path, string(src)))
+}
+
+func (i *Interpreter) run(l *lexer.Lexer) (interface{}, error) {
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return nil, &SyntaxError{Diagnostics: p.Diagnostics()}
+	}
+
+	if diagnostics := resolver.Resolve(program, i.env); len(diagnostics) != 0 {
+		return nil, &SyntaxError{Diagnostics: diagnostics}
+	}
+
+	return toResult(evaluator.Eval(program, i.env))
+}
+
+// Defines (or redefines) a global variable, value is converted to boar, see toObject. Constants can't be redefined
+func (i *Interpreter) Set(name string, value interface{}) error {
+	obj, err := toNamedObject(name, value)
+	if err != nil {
+		return fmt.Errorf("boar: can't set %s: %w", name, err)
+	}
+
+	if err, ok := i.env.Set(name, obj).(*object.Error); ok {
+		return fmt.Errorf("boar: can't set %s: %s", name, err.Message)
+	}
+	return nil
+}
+
+// The value of a global variable converted to Go, false if there's no such variable
+func (i *Interpreter) Get(name string) (interface{}, bool) {
+	obj, ok := i.env.Get(name)
+	if !ok {
+		return nil, false
+	}
+
+	return fromObject(obj), true
+}
+
+/**
+Calls the function stored in the global variable fnName, the arguments get converted to boar
+and the result back to Go. Works with functions defined by the program, builtins and registered Go functions.
+**/
+func (i *Interpreter) Call(fnName string, args ...interface{}) (interface{}, error) {
+	fn, ok := i.env.Get(fnName)
+	if !ok {
+		return nil, fmt.Errorf("boar: %s is not defined", fnName)
+	}
+
+	switch fn.(type) {
+	case *object.Function, *object.Builtin:
+	default:
+		return nil, fmt.Errorf("boar: %s is not a function, got %s", fnName, fn.Type())
+	}
+
+	objArgs := make([]object.Object, len(args))
+	for idx, arg := range args {
+		obj, err := toObject(arg)
+		if err != nil {
+			return nil, fmt.Errorf("boar: argument %d to %s: %w", idx+1, fnName, err)
+		}
+		objArgs[idx] = obj
+	}
+
+	return toResult(evaluator.CallFunction(fn, objArgs, i.env))
+}
+
+/**
+Makes a Go function callable from boar code as name(...).
+
+The arguments get converted to the function's parameter types (variadic functions are fine),
+a wrong number of arguments or an argument that doesn't fit is an ArgumentError / TypeError in the program.
+The function can return nothing, a value, an error or a value and an error,
+a non nil error becomes a RuntimeError in the program.
+**/
+func (i *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func {
+		return fmt.Errorf("boar: can't register %s: expected a function, got %T", name, fn)
+	}
+
+	return i.Set(name, fn)
+}
+
+// The value of a program converted to Go, or the error it failed with
+func toResult(obj object.Object) (interface{}, error) {
+	if err, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{Err: err}
+	}
+
+	return fromObject(obj), nil
+}
+
+// Returned when the source code doesn't parse or uses undefined variables, holds every problem found before running it
+type SyntaxError struct {
+	Diagnostics []*diagnostic.Diagnostic
+}
+
+// "line:column: message" for every error, one per line
+func (e *SyntaxError) Error() string {
+	messages := []string{}
+
+	for _, d := range e.Diagnostics {
+		if d.Severity == diagnostic.ERROR {
+			messages = append(messages, d.String())
+		}
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+// Returned when the program fails while running: runtime errors and uncaught throws
+type RuntimeError struct {
+	Err *object.Error
+}
+
+// "line:column: Kind: message"
+func (e *RuntimeError) Error() string {
+	msg := e.Err.KindName() + ": " + e.Err.Message
+
+	if e.Err.Pos.IsValid() {
+		msg = e.Err.Pos.String() + ": " + msg
+	}
+
+	return msg
+}
+
+// TypeError, ZeroDivisionError, etc. The kind of a thrown hash if it had one
+func (e *RuntimeError) Kind() string {
+	return e.Err.KindName()
+}
+
+// The error along with the boar calls it went through
+func (e *RuntimeError) StackTrace() string {
+	return e.Err.StackTrace()
+}
@@ -0,0 +1,395 @@
+package boar
+
+import (
+	"boar/object"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 + 2", int64(3)},
+		{"2.5 * 2", 5.0},
+		{`"boar" + "!"`, "boar!"},
+		{"1 < 2", true},
+		{"let x = 1", nil},
+		{"[1, \"a\", [true]]", []interface{}{int64(1), "a", []interface{}{true}}},
+		{`{"host": "localhost", "ports": [80, 443]}`, map[string]interface{}{"host": "localhost", "ports": []interface{}{int64(80), int64(443)}}},
+		{`{1: "one", "two": 2}`, map[interface{}]interface{}{int64(1): "one", "two": int64(2)}},
+	}
+
+	for _, tt := range tests {
+		result, err := New().Eval(tt.input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tt.input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("wrong result for %q. expected=%#v, got=%#v", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestEvalKeepsState(t *testing.T) {
+	interp := New()
+
+	if _, err := interp.Eval("let x = 20; fn double(n) { n * 2 }"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result, err := interp.Eval("double(x) + 2")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result != int64(42) {
+		t.Fatalf("expected 42, got %#v", result)
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	_, err := New().Eval("let x = ;")
+
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected a *SyntaxError, got %T (%v)", err, err)
+	}
+	if syntaxErr.Error() != "1:9: no prefix parse function for ; found" {
+		t.Errorf("wrong syntax error, got %q", syntaxErr.Error())
+	}
+
+	_, err = New().Eval("fn f(x) { x / 0 }\nf(1)")
+
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Fatalf("expected a *RuntimeError, got %T (%v)", err, err)
+	}
+	if runtimeErr.Error() != "1:13: ZeroDivisionError: division by zero: 1 / 0" {
+		t.Errorf("wrong runtime error, got %q", runtimeErr.Error())
+	}
+	if runtimeErr.Kind() != "ZeroDivisionError" {
+		t.Errorf("wrong error kind, got %q", runtimeErr.Kind())
+	}
+	if !strings.Contains(runtimeErr.StackTrace(), "at f(1) (2:2)") {
+		t.Errorf("expected the stack trace to contain the call to f, got %q", runtimeErr.StackTrace())
+	}
+
+	_, err = New().Eval(`throw {"kind": "ConfigError", "message": "missing host"}`)
+	if !errors.As(err, &runtimeErr) || runtimeErr.Kind() != "ConfigError" {
+		t.Errorf("expected a ConfigError, got %v", err)
+	}
+}
+
+func TestEvalFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.br")
+	if err := os.WriteFile(path, []byte("let retries = 3;\nretries * 2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := New().EvalFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if result != int64(6) {
+		t.Fatalf("expected 6, got %#v", result)
+	}
+
+	if err := os.WriteFile(path, []byte("let retries = 3;\nmissing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = New().EvalFile(path)
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || err.Error() != path+":2:1: identifier not found: missing" {
+		t.Fatalf("wrong error, got %v", err)
+	}
+
+	if _, err := New().EvalFile(filepath.Join(t.TempDir(), "missing.br")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a missing file error, got %v", err)
+	}
+}
+
+func TestSetAndGet(t *testing.T) {
+	interp := New()
+
+	values := map[string]interface{}{
+		"count":   3,
+		"small":   uint8(7),
+		"ratio":   float32(0.5),
+		"name":    "boar",
+		"enabled": true,
+		"nothing": nil,
+		"tags":    []string{"a", "b"},
+		"limits":  map[string]int{"cpu": 2},
+	}
+
+	for name, value := range values {
+		if err := interp.Set(name, value); err != nil {
+			t.Fatalf("unexpected error setting %s: %s", name, err)
+		}
+	}
+
+	result, err := interp.Eval(`if (enabled) { "${name} ${count + small} ${ratio * 2} ${tags[1]} ${limits["cpu"]}" }`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result != "boar 10 1.0 b 2" {
+		t.Errorf("wrong result, got %#v", result)
+	}
+
+	tags, ok := interp.Get("tags")
+	if !ok || !reflect.DeepEqual(tags, []interface{}{"a", "b"}) {
+		t.Errorf("wrong value for tags, got %#v", tags)
+	}
+
+	if value, ok := interp.Get("nothing"); !ok || value != nil {
+		t.Errorf("expected nothing to be nil, got %#v", value)
+	}
+
+	if _, ok := interp.Get("missing"); ok {
+		t.Errorf("expected missing to not be found")
+	}
+
+	if err := interp.Set("channel", make(chan int)); err == nil || err.Error() != "boar: can't set channel: unsupported Go type chan int" {
+		t.Errorf("wrong error for an unsupported type, got %v", err)
+	}
+
+	if err := interp.Set("huge", uint64(1<<63)); err == nil {
+		t.Errorf("expected an error for an integer that doesn't fit")
+	}
+
+	if _, err := interp.Eval("const limit = 1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := interp.Set("limit", 2); err == nil || err.Error() != "boar: can't set limit: cannot assign to constant limit" {
+		t.Errorf("wrong error for a constant, got %v", err)
+	}
+}
+
+func TestMapKeyOrder(t *testing.T) {
+	interp := New()
+
+	values := map[string]interface{}{
+		"names":  map[string]int{"delta": 4, "alpha": 1, "charlie": 3, "bravo": 2},
+		"ids":    map[int]string{10: "j", 9: "i", -1: "z", 100: "c"},
+		"mixed":  map[interface{}]int{"b": 1, 2: 2, true: 3, "a": 4, 1: 5},
+		"nested": map[string]interface{}{"z": map[string]int{"y": 1, "x": 2}},
+	}
+
+	for name, value := range values {
+		if err := interp.Set(name, value); err != nil {
+			t.Fatalf("unexpected error setting %s: %s", name, err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"keys(names)", "alpha bravo charlie delta"},
+		{"keys(ids)", "-1 9 10 100"},
+		{"keys(mixed)", "true 1 2 a b"},
+		{"keys(nested.z)", "x y"},
+	}
+
+	// maps come back in a different order every time they're iterated, a few rounds make sure it's not luck
+	for round := 0; round < 10; round++ {
+		for _, tt := range tests {
+			result, err := interp.Eval(tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error for %q: %s", tt.input, err)
+			}
+
+			keys := []string{}
+			for _, key := range result.([]interface{}) {
+				keys = append(keys, fmt.Sprint(key))
+			}
+
+			if strings.Join(keys, " ") != tt.expected {
+				t.Errorf("wrong order for %q, expected %s, got %v", tt.input, tt.expected, keys)
+			}
+		}
+
+		for name, value := range values {
+			interp.Set(name, value)
+		}
+	}
+}
+
+func TestSelfReferencingValues(t *testing.T) {
+	interp := New()
+
+	if _, err := interp.Eval(`let a = [1, 2]; a[0] = a; let h = {"n": 1}; h["self"] = h`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the repeated value stays a boar object, converting it again would never end
+	a, _ := interp.Get("a")
+	elements, ok := a.([]interface{})
+	if !ok || len(elements) != 2 || elements[1] != int64(2) {
+		t.Fatalf("wrong value for a, got %#v", a)
+	}
+	if inner, ok := elements[0].(*object.Array); !ok || inner.Inspect() != "[[...], 2]" {
+		t.Errorf("expected the inner a to be the boar array, got %#v", elements[0])
+	}
+
+	h, _ := interp.Get("h")
+	pairs, ok := h.(map[string]interface{})
+	if !ok || pairs["n"] != int64(1) {
+		t.Fatalf("wrong value for h, got %#v", h)
+	}
+	if inner, ok := pairs["self"].(*object.Hash); !ok || inner.Inspect() != `{"n" : "1", "self" : "{...}"}` {
+		t.Errorf("expected the inner h to be the boar hash, got %#v", pairs["self"])
+	}
+}
+
+func TestCall(t *testing.T) {
+	interp := New()
+
+	if _, err := interp.Eval(`fn greet(name, greeting = "Hello") { "${greeting}, ${name}!" }; fn fail() { throw "oops" }; let notFn = 1`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	result, err := interp.Call("greet", "Boar")
+	if err != nil || result != "Hello, Boar!" {
+		t.Errorf("wrong result for greet, got %#v (%v)", result, err)
+	}
+
+	result, err = interp.Call("len", []int{1, 2, 3})
+	if err != nil || result != int64(3) {
+		t.Errorf("wrong result for len, got %#v (%v)", result, err)
+	}
+
+	tests := []struct {
+		fnName   string
+		args     []interface{}
+		expected string
+	}{
+		{"fail", nil, "1:77: Error: oops"},
+		{"greet", nil, "ArgumentError: wrong number of arguments passed to greet. Got 0 wanted 1 to 2"},
+		{"missing", nil, "boar: missing is not defined"},
+		{"notFn", nil, "boar: notFn is not a function, got INTEGER"},
+		{"greet", []interface{}{make(chan int)}, "boar: argument 1 to greet: unsupported Go type chan int"},
+	}
+
+	for _, tt := range tests {
+		_, err := interp.Call(tt.fnName, tt.args...)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error for %s, expected %q, got %v", tt.fnName, tt.expected, err)
+		}
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	interp := New()
+
+	funcs := map[string]interface{}{
+		"add":  func(a, b int) int { return a + b },
+		"half": func(x float64) float64 { return x / 2 },
+		"join": strings.Join,
+		"sum": func(nums ...int) int {
+			total := 0
+			for _, n := range nums {
+				total += n
+			}
+			return total
+		},
+		"check": func(ok bool) error {
+			if !ok {
+				return errors.New("check failed")
+			}
+			return nil
+		},
+		"parse": func(s string) (map[string]interface{}, error) { return map[string]interface{}{"value": s}, nil },
+		"keys":  func(m map[string]int) int { return len(m) },
+		"first": func(values []interface{}) interface{} { return values[0] },
+		"boom":  func() { panic("boom") },
+	}
+
+	for name, fn := range funcs {
+		if err := interp.RegisterFunc(name, fn); err != nil {
+			t.Fatalf("unexpected error registering %s: %s", name, err)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"add(1, 2)", int64(3)},
+		{"half(3)", 1.5},
+		{`join(["a", "b"], "-")`, "a-b"},
+		{"sum()", int64(0)},
+		{"sum(1, 2, 3)", int64(6)},
+		{"check(true)", nil},
+		{`parse("x").value`, "x"},
+		{`keys({"a": 1, "b": 2})`, int64(2)},
+		{`first(["a", 1])`, "a"},
+		// registered functions are values like any other
+		{"[1, 2].map(fn(x) { add(x, 10) })", []interface{}{int64(11), int64(12)}},
+		{`try { check(false) } catch (e) { e.message }`, "check failed"},
+	}
+
+	for _, tt := range tests {
+		result, err := interp.Eval(tt.input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tt.input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("wrong result for %q. expected=%#v, got=%#v", tt.input, tt.expected, result)
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(1)", "1:4: ArgumentError: wrong number of arguments passed to add. Got 1 wanted 2"},
+		{`add(1, "2")`, "1:4: TypeError: argument 2 to add: expected int, got STRING"},
+		{"half(true)", "1:5: TypeError: argument 1 to half: expected float64, got BOOLEAN"},
+		{"check(false)", "1:6: RuntimeError: check failed"},
+		{"boom()", "1:5: RuntimeError: boom panicked: boom"},
+	}
+
+	for _, tt := range errorTests {
+		_, err := interp.Eval(tt.input)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+
+	if err := interp.RegisterFunc("notFn", 1); err == nil || err.Error() != "boar: can't register notFn: expected a function, got int" {
+		t.Errorf("wrong error for a value that isn't a function, got %v", err)
+	}
+
+	pair := func() (int, int) { return 1, 2 }
+	if err := interp.RegisterFunc("pair", pair); err == nil {
+		t.Errorf("expected an error for a function returning two values")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	var out bytes.Buffer
+	interp := New(WithStdout(&out), WithStdin(strings.NewReader("Boar\n")))
+
+	if _, err := interp.Eval(`puts("hi ${gets("name: ")}")`); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if out.String() != "name: hi Boar\n" {
+		t.Errorf("wrong output, got %q", out.String())
+	}
+}
@@ -0,0 +1,228 @@
+package diagnostic
+
+import (
+	"boar/token"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// How bad a diagnostic is
+type Severity int
+
+const (
+	ERROR Severity = iota
+	WARNING
+	NOTE
+)
+
+var severityNames = map[Severity]string{
+	ERROR:   "error",
+	WARNING: "warning",
+	NOTE:    "note",
+}
+
+func (s Severity) String() string {
+	if name, ok := severityNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Severities are encoded as their names ("error", "warning", etc) so tools don't need to know our constants
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+/**
+Stable, machine-readable identifier for each kind of diagnostic.
+Codes are never reused, retired ones stay reserved so tools keyed on them don't change meaning:
+- E0005: method call on an expression that couldn't have methods, every expression supports .method() calls now
+**/
+type Code string
+
+const (
+	UNEXPECTED_TOKEN          Code = "E0001" // expected one token, got another
+	NO_PREFIX_PARSE_FN        Code = "E0002" // token can't start an expression
+	INVALID_INTEGER           Code = "E0003" // integer literal we couldn't convert
+	INVALID_ASSIGNMENT_TARGET Code = "E0004" // left side of = can't be assigned to
+	INVALID_FOR_LOOP          Code = "E0006" // for loop header doesn't match for (let ...; ...; ... = ...)
+	ILLEGAL_CHARACTER         Code = "E0007" // character that isn't part of the language
+	UNTERMINATED_COMMENT      Code = "E0008" // /* without a matching */
+	INVALID_FLOAT             Code = "E0009" // float literal we couldn't convert
+	UNTERMINATED_STRING       Code = "E0010" // " or ` without a matching closing quote
+	INVALID_ESCAPE            Code = "E0011" // unknown or malformed escape sequence: \q, \x4, etc
+	OUTSIDE_LOOP              Code = "E0012" // break / continue that isn't inside of a loop
+	INVALID_PATTERN           Code = "E0013" // match arm pattern that isn't a literal or _
+	INVALID_PARAMETER         Code = "E0014" // duplicate parameter, rest parameter that isn't the last one, etc
+	INVALID_ARGUMENT          Code = "E0015" // positional argument after a named one, repeated named argument
+	INVALID_TRY               Code = "E0016" // try block without a catch or finally block
+	TOO_DEEPLY_NESTED         Code = "E0017" // expressions / blocks nested deeper than the parser allows
+	UNDEFINED_VARIABLE        Code = "E0018" // identifier that isn't declared anywhere, reported by the resolver
+)
+
+/**
+The source code range a diagnostic points at.
+- Start is the position of the first character
+- End is the position right after the last character
+**/
+type Span struct {
+	Start token.Position `json:"start"`
+	End   token.Position `json:"end"`
+}
+
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	Code     Code     `json:"code"`
+	Message  string   `json:"message"`
+	Span     Span     `json:"span"`
+	Hints    []string `json:"hints,omitempty"` // optional suggestions on how to fix the problem
+}
+
+// Creates an error diagnostic pointing at the given token
+func NewError(code Code, tok token.Token, format string, a ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Severity: ERROR,
+		Code:     code,
+		Message:  fmt.Sprintf(format, a...),
+		Span:     TokenSpan(tok),
+	}
+}
+
+/**
+Returns the span the token covers in the source.
+Tokens made up outside of the lexer have no End, their span is as long as their literal.
+**/
+func TokenSpan(tok token.Token) Span {
+	if tok.End.IsValid() {
+		return Span{Start: tok.Pos, End: tok.End}
+	}
+
+	end := tok.Pos
+	end.Column += utf8.RuneCountInString(tok.Literal)
+	end.Offset += len(tok.Literal)
+
+	return Span{Start: tok.Pos, End: end}
+}
+
+// Adds a hint to the diagnostic, returns the diagnostic so calls can be chained
+func (d *Diagnostic) WithHint(format string, a ...interface{}) *Diagnostic {
+	d.Hints = append(d.Hints, fmt.Sprintf(format, a...))
+	return d
+}
+
+// line:column: message
+func (d *Diagnostic) String() string {
+	return d.Span.Start.String() + ": " + d.Message
+}
+
+// Allows a diagnostic to be used anywhere a Go error is expected
+func (d *Diagnostic) Error() string {
+	return d.String()
+}
+
+/**
+Writes the diagnostic along with the offending source line, underlining the span with carets:
+
+error[E0001]: expected next token to be =, got INT instead
+ --> test.br:1:7
+  |
+1 | let x 5;
+  |       ^
+  = hint: let statements need a value: let <identifier> = <expression>;
+**/
+func Render(out io.Writer, source string, d *Diagnostic) {
+	var buf bytes.Buffer
+
+	start := d.Span.Start
+	lineNumber := fmt.Sprintf("%d", start.Line)
+	gutter := strings.Repeat(" ", len(lineNumber))
+
+	buf.WriteString(fmt.Sprintf("%s[%s]: %s\n", d.Severity, d.Code, d.Message))
+	buf.WriteString(fmt.Sprintf("%s--> %s\n", gutter, start))
+
+	if line, ok := sourceLine(source, start.Line); ok {
+		buf.WriteString(gutter + " |\n")
+		buf.WriteString(lineNumber + " | " + line + "\n")
+		buf.WriteString(gutter + " | " + underline(line, d.Span) + "\n")
+	}
+
+	for _, hint := range d.Hints {
+		buf.WriteString(gutter + " = hint: " + hint + "\n")
+	}
+
+	io.WriteString(out, buf.String())
+}
+
+// Renders every diagnostic, separated by a blank line
+func RenderAll(out io.Writer, source string, diagnostics []*Diagnostic) {
+	for idx, d := range diagnostics {
+		if idx > 0 {
+			io.WriteString(out, "\n")
+		}
+		Render(out, source, d)
+	}
+}
+
+// Returns the (1-based) line of the source, without its line break
+func sourceLine(source string, line int) (string, bool) {
+	lines := strings.Split(source, "\n")
+
+	if line < 1 || line > len(lines) {
+		return "", false
+	}
+
+	return strings.TrimRight(lines[line-1], "\r"), true
+}
+
+/**
+Builds the caret line for the span.
+- Tabs before the span are kept as tabs so the carets line up with the source line.
+- Spans that are empty (EOF) or that continue on the next lines get at least one caret.
+**/
+func underline(line string, span Span) string {
+	var out bytes.Buffer
+
+	startCol := span.Start.Column
+	if startCol < 1 {
+		startCol = 1
+	}
+
+	// columns count characters, not bytes
+	chars := []rune(line)
+
+	for i := 0; i < startCol-1 && i < len(chars); i++ {
+		if chars[i] == '\t' {
+			out.WriteByte('\t')
+		} else {
+			out.WriteByte(' ')
+		}
+	}
+
+	// pointing past the end of the line (i.e. EOF), keep padding with spaces
+	for i := len(chars); i < startCol-1; i++ {
+		out.WriteByte(' ')
+	}
+
+	width := 1
+	if span.End.Line == span.Start.Line && span.End.Column > startCol {
+		width = span.End.Column - startCol
+	}
+
+	out.WriteString(strings.Repeat("^", width))
+
+	return out.String()
+}
+
+/**
+Dev Notes:
+
+Diagnostics:
+- A diagnostic is a structured error (or warning) message: what went wrong, where it went wrong and
+  (sometimes) how to fix it.
+- Unlike plain error strings, diagnostics can be consumed by other tools (editors, CI annotators, etc),
+  either directly or after encoding them to JSON.
+- Render() turns them back into something friendly for humans, similar to what rustc or go vet print.
+**/
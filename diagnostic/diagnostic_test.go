@@ -0,0 +1,103 @@
+package diagnostic
+
+import (
+	"boar/token"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	source := "let x = 1;\nlet y = x +* 2;"
+	tok := token.Token{Type: token.ASTERISK, Literal: "*", Pos: token.Position{File: "test.br", Line: 2, Column: 12, Offset: 22}}
+
+	d := NewError(NO_PREFIX_PARSE_FN, tok, "no prefix parse function for %s found", tok.Type).
+		WithHint("remove the extra operator")
+
+	var out bytes.Buffer
+	Render(&out, source, d)
+
+	expected := strings.Join([]string{
+		"error[E0002]: no prefix parse function for * found",
+		" --> test.br:2:12",
+		"  |",
+		"2 | let y = x +* 2;",
+		"  |            ^",
+		"  = hint: remove the extra operator",
+		"",
+	}, "\n")
+
+	if out.String() != expected {
+		t.Errorf("wrong render output.\nexpected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestRenderUnderlinesWholeToken(t *testing.T) {
+	source := "\tfoobar + 1"
+	tok := token.Token{Type: token.IDENT, Literal: "foobar", Pos: token.Position{Line: 1, Column: 2, Offset: 1}}
+
+	var out bytes.Buffer
+	Render(&out, source, NewError(UNEXPECTED_TOKEN, tok, "unexpected identifier"))
+
+	lines := strings.Split(out.String(), "\n")
+	caretLine := lines[4]
+
+	if caretLine != "  | \t^^^^^^" {
+		t.Errorf("wrong caret line, got %q", caretLine)
+	}
+}
+
+func TestRenderUnicodeSource(t *testing.T) {
+	source := `let s = "héllo" + café;`
+	tok := token.Token{Type: token.IDENT, Literal: "café", Pos: token.Position{Line: 1, Column: 19, Offset: 19}}
+
+	var out bytes.Buffer
+	Render(&out, source, NewError(UNEXPECTED_TOKEN, tok, "unexpected identifier"))
+
+	lines := strings.Split(out.String(), "\n")
+	caretLine := lines[4]
+
+	// columns count characters, so the carets line up under café
+	if caretLine != "  |"+strings.Repeat(" ", 19)+"^^^^" {
+		t.Errorf("wrong caret line, got %q", caretLine)
+	}
+}
+
+func TestDiagnosticString(t *testing.T) {
+	tok := token.Token{Type: token.EOF, Literal: "", Pos: token.Position{Line: 1, Column: 6, Offset: 5}}
+	d := NewError(UNEXPECTED_TOKEN, tok, "expected next token to be =, got EOF instead")
+
+	if d.String() != "1:6: expected next token to be =, got EOF instead" {
+		t.Errorf("wrong diagnostic string, got %q", d.String())
+	}
+
+	if d.Span.End.Column != 6 {
+		t.Errorf("empty token should have an empty span, got end column %d", d.Span.End.Column)
+	}
+}
+
+func TestDiagnosticJSON(t *testing.T) {
+	tok := token.Token{Type: token.INT, Literal: "5", Pos: token.Position{Line: 1, Column: 7, Offset: 6}}
+	d := NewError(UNEXPECTED_TOKEN, tok, "expected next token to be =, got INT instead")
+
+	encoded, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("could not encode diagnostic: %s", err)
+	}
+
+	decoded := map[string]interface{}{}
+	json.Unmarshal(encoded, &decoded)
+
+	if decoded["severity"] != "error" {
+		t.Errorf("severity not encoded as its name, got %v", decoded["severity"])
+	}
+
+	if decoded["code"] != "E0001" {
+		t.Errorf("wrong code, got %v", decoded["code"])
+	}
+
+	if _, ok := decoded["span"].(map[string]interface{}); !ok {
+		t.Errorf("span not encoded, got %v", decoded["span"])
+	}
+}
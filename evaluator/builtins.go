@@ -3,11 +3,15 @@ package evaluator
 import (
 	"boar/object"
 	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
 )
 
 type ErrorFormatter struct {
 	FuncName          string
-	ArgumentsExpected int //minimum arguments expected
+	ArgumentsExpected int //maximum arguments expected for arrays, minimum for hashes
+	MinArguments      int //array functions: minimum arguments expected, 1 (the array) if not set
 	Arguments         []object.Object
 }
 
@@ -19,35 +23,55 @@ var BUILTIN = map[string]*object.Builtin{
 	"rest":     {Fn: __rest__},
 	"push":     {Fn: __push__},
 	"puts":     {Fn: __puts__},
+	"gets":     {Fn: __gets__},
+	"readLine": {Fn: __gets__},
 	"delete":   {Fn: __delete__},
 	"valuesAt": {Fn: __valuesAt__},
 	"toArray":  {Fn: __toArray__},
 	"dig":      {Fn: __dig__},
+	"keys":     {Fn: __keys__},
+	"values":   {Fn: __values__},
+	"entries":  {Fn: __entries__},
+	"has":      {Fn: __has__},
+	"merge":    {Fn: __merge__},
+	"size":     {Fn: __size__},
 	"map":      {Fn: __map__},
 	"pop":      {Fn: __pop__},
 	"shift":    {Fn: __shift__},
 	"slice":    {Fn: __slice__},
+	"bytes":    {Fn: __bytes__},
+	"range":    {Fn: __range__},
+	"freeze":   {Fn: __freeze__},
 }
 
 func checkForArrayErrors(formatter ErrorFormatter) object.Object {
 	args := formatter.Arguments
 	functionName := formatter.FuncName
 	argumentsExpected := formatter.ArgumentsExpected
-	if len(args) > argumentsExpected {
-		return newError("wrong number of arguments passed to %s. Got %d wanted %d", functionName, len(args), argumentsExpected)
+	minArguments := formatter.MinArguments
+	if minArguments == 0 {
+		minArguments = 1
+	}
+
+	if len(args) < minArguments || len(args) > argumentsExpected {
+		wanted := fmt.Sprintf("%d", argumentsExpected)
+		if minArguments != argumentsExpected {
+			wanted = fmt.Sprintf("%d to %d", minArguments, argumentsExpected)
+		}
+		return newErrorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments passed to %s. Got %d wanted %s", functionName, len(args), wanted)
 	}
 
 	if !isArray(args[0]) {
-		return newError("argument to `%s` must be ARRAY, got %s", functionName, args[0].Type())
+		return newErrorOfKind(object.TYPE_ERROR, "argument to `%s` must be ARRAY, got %s", functionName, typeOf(args[0]))
 	}
 
 	return NULL
 }
 
-func __len__(args ...object.Object) object.Object {
+func __len__(ctx *object.Context, args ...object.Object) object.Object {
 	// len() should only be passed 1 argument
 	if len(args) != 1 {
-		return newError("wrong number of arguments. got %d, wanted 1", len(args))
+		return newErrorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments. got %d, wanted 1", len(args))
 	}
 
 	switch arg := args[0].(type) {
@@ -55,15 +79,16 @@ func __len__(args ...object.Object) object.Object {
 	case *object.Array:
 		return &object.Integer{Value: int64(len(arg.Elements))}
 
+	// number of characters, not bytes: len("héllo") == 5
 	case *object.String:
-		return &object.Integer{Value: int64(len(arg.Value))}
+		return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 
 	default:
-		return newError("argument to `len` not supported, got %s", args[0].Type())
+		return newErrorOfKind(object.TYPE_ERROR, "argument to `len` not supported, got %s", args[0].Type())
 	}
 }
 
-func __first__(args ...object.Object) object.Object {
+func __first__(ctx *object.Context, args ...object.Object) object.Object {
 	err := checkForArrayErrors(ErrorFormatter{FuncName: "first", ArgumentsExpected: 1, Arguments: args})
 
 	if err != NULL {
@@ -79,7 +104,7 @@ func __first__(args ...object.Object) object.Object {
 	return NULL
 }
 
-func __last__(args ...object.Object) object.Object {
+func __last__(ctx *object.Context, args ...object.Object) object.Object {
 	err := checkForArrayErrors(ErrorFormatter{FuncName: "last", ArgumentsExpected: 1, Arguments: args})
 
 	if err != NULL {
@@ -101,7 +126,7 @@ func __last__(args ...object.Object) object.Object {
 - returns a new array containing all the elements of the array passed as argument *except* the first one.
 - Similar to the cdr function in Scheme (also similar to tail)
 **/
-func __rest__(args ...object.Object) object.Object {
+func __rest__(ctx *object.Context, args ...object.Object) object.Object {
 	err := checkForArrayErrors(ErrorFormatter{FuncName: "rest", ArgumentsExpected: 1, Arguments: args})
 
 	if err != NULL {
@@ -126,8 +151,8 @@ func __rest__(args ...object.Object) object.Object {
 - Returns a new array with the pushed element at the end.
 - Arrays are immutable in boar-lang, so it doesn't modify the given array
 **/
-func __push__(args ...object.Object) object.Object {
-	err := checkForArrayErrors(ErrorFormatter{FuncName: "push", ArgumentsExpected: 2, Arguments: args})
+func __push__(ctx *object.Context, args ...object.Object) object.Object {
+	err := checkForArrayErrors(ErrorFormatter{FuncName: "push", ArgumentsExpected: 2, MinArguments: 2, Arguments: args})
 
 	if err != NULL {
 		return err
@@ -143,15 +168,53 @@ func __push__(args ...object.Object) object.Object {
 	return &object.Array{Elements: newElements}
 }
 
-func __puts__(args ...object.Object) object.Object {
+func __puts__(ctx *object.Context, args ...object.Object) object.Object {
 	for _, arg := range args {
-		fmt.Println(arg.Inspect())
+		if arg == nil {
+			arg = NULL
+		}
+		fmt.Fprintln(ctx.Stdout, arg.Inspect())
 	}
 
 	return NULL
 }
 
-func __delete__(args ...object.Object) object.Object {
+/**
+gets() / readLine() reads the next line from the program's stdin, without the line break.
+- gets("name: ") writes the prompt to stdout first
+- returns null once there's nothing left to read
+**/
+func __gets__(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return newErrorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments passed to gets. Got %d wanted 0 or 1", len(args))
+	}
+
+	if len(args) == 1 {
+		prompt, ok := args[0].(*object.String)
+		if !ok {
+			return newErrorOfKind(object.TYPE_ERROR, "argument to `gets` must be STRING, got %s", typeOf(args[0]))
+		}
+		io.WriteString(ctx.Stdout, prompt.Value)
+	}
+
+	line, err := ctx.Stdin.ReadString('\n')
+
+	// the last line doesn't need a line break
+	if err == io.EOF && line == "" {
+		return NULL
+	}
+
+	if err != nil && err != io.EOF {
+		return newError("couldn't read from stdin: %s", err)
+	}
+
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+
+	return &object.String{Value: line}
+}
+
+func __delete__(ctx *object.Context, args ...object.Object) object.Object {
 	err := checkForHashErrors(ErrorFormatter{FuncName: "delete", ArgumentsExpected: 2, Arguments: args})
 
 	if err != NULL {
@@ -161,22 +224,26 @@ func __delete__(args ...object.Object) object.Object {
 	// First argument must be a hash
 	hash := args[0].(*object.Hash)
 
+	if hash.Frozen {
+		return frozenError(hash)
+	}
+
 	// The remaining arguments should be valid hash keys.
-	// Loop through them and null their values
+	// Loop through them and remove them, keys that aren't in the hash are ignored
 	for _, arg := range args[1:] {
 		hashKey, ok := arg.(object.Hashable)
 
 		if !ok {
-			return newError("Unusable value as hash key: %s", arg.Type())
+			return newErrorOfKind(object.TYPE_ERROR, "Unusable value as hash key: %s", arg.Type())
 		}
 
-		hash.Pairs[hashKey.HashKey()] = object.HashPair{Key: NULL, Value: NULL}
+		hash.Delete(hashKey.HashKey())
 	}
 
 	return hash
 }
 
-func __valuesAt__(args ...object.Object) object.Object {
+func __valuesAt__(ctx *object.Context, args ...object.Object) object.Object {
 	err := checkForHashErrors(ErrorFormatter{FuncName: "valuesAt", ArgumentsExpected: 2, Arguments: args})
 
 	if err != NULL {
@@ -194,17 +261,21 @@ func __valuesAt__(args ...object.Object) object.Object {
 		hashKey, ok := arg.(object.Hashable)
 
 		if !ok {
-			return newError("Unusable value as hash key: %s", arg.Type())
+			return newErrorOfKind(object.TYPE_ERROR, "Unusable value as hash key: %s", arg.Type())
 		}
 
-		// Grab the value at said key, append to array
-		arr.Elements = append(arr.Elements, hash.Pairs[hashKey.HashKey()].Value)
+		// Grab the value at said key (null if there isn't one), append to array
+		var value object.Object = NULL
+		if pair, exists := hash.Get(hashKey.HashKey()); exists {
+			value = pair.Value
+		}
+		arr.Elements = append(arr.Elements, value)
 	}
 
 	return arr
 }
 
-func __toArray__(args ...object.Object) object.Object {
+func __toArray__(ctx *object.Context, args ...object.Object) object.Object {
 	err := checkForHashErrors(ErrorFormatter{FuncName: "toArray", ArgumentsExpected: 1, Arguments: args})
 
 	if err != NULL {
@@ -217,14 +288,14 @@ func __toArray__(args ...object.Object) object.Object {
 	// Create array object to store object values at x key
 	arr := &object.Array{}
 
-	for _, pair := range hash.Pairs {
+	for _, pair := range hash.Pairs() {
 		arr.Elements = append(arr.Elements, pair.Key, pair.Value)
 	}
 
 	return arr
 }
 
-func __dig__(args ...object.Object) object.Object {
+func __dig__(ctx *object.Context, args ...object.Object) object.Object {
 	err := checkForHashErrors(ErrorFormatter{FuncName: "dig", ArgumentsExpected: 2, Arguments: args})
 
 	if err != NULL {
@@ -241,10 +312,10 @@ func __dig__(args ...object.Object) object.Object {
 	hashKey, ok := args[1].(object.Hashable)
 
 	if !ok {
-		return newError("Unusable value as hash key: %s", args[1].Type())
+		return newErrorOfKind(object.TYPE_ERROR, "Unusable value as hash key: %s", args[1].Type())
 	}
 
-	extracted, exists := hash.Pairs[hashKey.HashKey()]
+	extracted, exists := hash.Get(hashKey.HashKey())
 
 	if exists {
 		// if we only have 2 args (someInnerHash, key), and we've found the value exists then return it
@@ -254,14 +325,123 @@ func __dig__(args ...object.Object) object.Object {
 
 		newArgs := append([]object.Object{extracted.Value}, args[2:]...)
 
-		return __dig__(newArgs...)
+		return __dig__(ctx, newArgs...)
+	}
+
+	return NULL
+}
+
+// keys(hash): the keys in the order they were added
+func __keys__(ctx *object.Context, args ...object.Object) object.Object {
+	err := checkForHashErrors(ErrorFormatter{FuncName: "keys", ArgumentsExpected: 1, Arguments: args})
+
+	if err != NULL {
+		return err
+	}
+
+	arr := &object.Array{Elements: []object.Object{}}
+
+	for _, pair := range args[0].(*object.Hash).Pairs() {
+		arr.Elements = append(arr.Elements, pair.Key)
+	}
+
+	return arr
+}
+
+// values(hash): the values in the order their keys were added
+func __values__(ctx *object.Context, args ...object.Object) object.Object {
+	err := checkForHashErrors(ErrorFormatter{FuncName: "values", ArgumentsExpected: 1, Arguments: args})
+
+	if err != NULL {
+		return err
+	}
+
+	arr := &object.Array{Elements: []object.Object{}}
+
+	for _, pair := range args[0].(*object.Hash).Pairs() {
+		arr.Elements = append(arr.Elements, pair.Value)
+	}
+
+	return arr
+}
+
+// entries(hash): [[key, value], ...], unlike toArray() every pair is an array of its own
+func __entries__(ctx *object.Context, args ...object.Object) object.Object {
+	err := checkForHashErrors(ErrorFormatter{FuncName: "entries", ArgumentsExpected: 1, Arguments: args})
+
+	if err != NULL {
+		return err
+	}
+
+	arr := &object.Array{Elements: []object.Object{}}
+
+	for _, pair := range args[0].(*object.Hash).Pairs() {
+		arr.Elements = append(arr.Elements, &object.Array{Elements: []object.Object{pair.Key, pair.Value}})
+	}
+
+	return arr
+}
+
+// has(hash, key): whether the key is in the hash, even if its value is null
+func __has__(ctx *object.Context, args ...object.Object) object.Object {
+	err := checkForHashErrors(ErrorFormatter{FuncName: "has", ArgumentsExpected: 2, Arguments: args})
+
+	if err != NULL {
+		return err
+	}
+
+	hashKey, ok := args[1].(object.Hashable)
+
+	if !ok {
+		return newErrorOfKind(object.TYPE_ERROR, "Unusable value as hash key: %s", args[1].Type())
+	}
+
+	_, exists := args[0].(*object.Hash).Get(hashKey.HashKey())
+
+	return nativeBoolToBooleanObject(exists)
+}
+
+/**
+merge(hash, others...): a new hash with the pairs of every hash, later ones win when a key is in more than one.
+None of the hashes change, so frozen ones can be merged too (the result isn't frozen).
+**/
+func __merge__(ctx *object.Context, args ...object.Object) object.Object {
+	err := checkForHashErrors(ErrorFormatter{FuncName: "merge", ArgumentsExpected: 1, Arguments: args})
+
+	if err != NULL {
+		return err
+	}
+
+	merged := &object.Hash{}
+
+	for _, arg := range args {
+		hash, ok := arg.(*object.Hash)
+
+		if !ok {
+			return newErrorOfKind(object.TYPE_ERROR, "argument to `merge` must be HASH, got %s", arg.Type())
+		}
+
+		for _, pair := range hash.Pairs() {
+			merged.Set(pair.Key.(object.Hashable).HashKey(), pair)
+		}
+	}
+
+	return merged
+}
+
+// size(hash): the number of keys
+func __size__(ctx *object.Context, args ...object.Object) object.Object {
+	err := checkForHashErrors(ErrorFormatter{FuncName: "size", ArgumentsExpected: 1, Arguments: args})
+
+	if err != NULL {
+		return err
 	}
 
-	return nil
+	return &object.Integer{Value: int64(args[0].(*object.Hash).Len())}
 }
 
-func __map__(args ...object.Object) object.Object {
-	err := checkForArrayErrors(ErrorFormatter{FuncName: "map", ArgumentsExpected: 2, Arguments: args})
+func __map__(ctx *object.Context, args ...object.Object) object.Object {
+	err := checkForArrayErrors(ErrorFormatter{FuncName: "map", ArgumentsExpected: 2, MinArguments: 2, Arguments: args})
 
 	if err != NULL {
 		return err
@@ -269,17 +449,15 @@ func __map__(args ...object.Object) object.Object {
 
 	//Grab the function from the args
 	function, exists := args[1].(*object.Function)
-	// remove the function from the list, so now we should only have the array arg
-	args = args[0:1]
 
 	if !exists {
-		return newError("second arguments to `map` should be a function, got %T instead", function.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "second argument to `map` should be a function, got %s instead", typeOf(args[1]))
 	}
 
-	return applyFunction(function, args)
+	return applyMapCall(ctx, args[0].(*object.Array), function)
 }
 
-func __pop__(args ...object.Object) object.Object {
+func __pop__(ctx *object.Context, args ...object.Object) object.Object {
 	err := checkForArrayErrors(ErrorFormatter{FuncName: "pop", ArgumentsExpected: 1, Arguments: args})
 
 	if err != NULL {
@@ -288,6 +466,14 @@ func __pop__(args ...object.Object) object.Object {
 
 	arr := args[0].(*object.Array)
 
+	if arr.Frozen {
+		return frozenError(arr)
+	}
+
+	if len(arr.Elements) == 0 {
+		return newErrorOfKind(object.INDEX_ERROR, "pop from an empty array")
+	}
+
 	val := arr.Elements[len(arr.Elements)-1]
 
 	arr.Elements = arr.Elements[0 : len(arr.Elements)-1]
@@ -295,7 +481,7 @@ func __pop__(args ...object.Object) object.Object {
 	return val
 }
 
-func __shift__(args ...object.Object) object.Object {
+func __shift__(ctx *object.Context, args ...object.Object) object.Object {
 	err := checkForArrayErrors(ErrorFormatter{FuncName: "shift", ArgumentsExpected: 1, Arguments: args})
 
 	if err != NULL {
@@ -304,6 +490,14 @@ func __shift__(args ...object.Object) object.Object {
 
 	arr := args[0].(*object.Array)
 
+	if arr.Frozen {
+		return frozenError(arr)
+	}
+
+	if len(arr.Elements) == 0 {
+		return newErrorOfKind(object.INDEX_ERROR, "shift from an empty array")
+	}
+
 	val := arr.Elements[0]
 
 	arr.Elements = arr.Elements[1:]
@@ -311,7 +505,11 @@ func __shift__(args ...object.Object) object.Object {
 	return val
 }
 
-func __slice__(args ...object.Object) object.Object {
+func __slice__(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) > 0 && isString(args[0]) {
+		return sliceString(args)
+	}
+
 	err := checkForArrayErrors(ErrorFormatter{FuncName: "slice", ArgumentsExpected: 3, Arguments: args})
 
 	if err != NULL {
@@ -319,58 +517,186 @@ func __slice__(args ...object.Object) object.Object {
 	}
 
 	if len(args) > 3 {
-		return newError("Too many values passed to `slice`, expected 3 at most, got %d instead", len(args))
+		return newErrorOfKind(object.ARGUMENT_ERROR, "Too many values passed to `slice`, expected 3 at most, got %d instead", len(args))
 	}
 
 	arr := args[0].(*object.Array)
-	arrCopy := &object.Array{Elements: arr.Elements[:]}
+	// same rules as strings: end defaults to the length of the array, indexes past it get clamped
+	indexes := []int64{0, int64(len(arr.Elements))}
 
-	// If its just an array, return a copy of the array
-	if len(args[1:]) == 0 {
-		return arrCopy
+	// Make sure all other args are int values
+	for idx, arg := range args[1:] {
+		obj, isInt := arg.(*object.Integer)
+
+		if !isInt {
+			return newErrorOfKind(object.TYPE_ERROR, "expected an integer, got a type of %s instead", typeOf(arg))
+		}
+
+		if obj.Value < 0 {
+			return newErrorOfKind(object.ARGUMENT_ERROR, "Negative indexes not supported (yet), recieved value of %d", obj.Value)
+		}
+
+		indexes[idx] = obj.Value
+		if indexes[idx] > int64(len(arr.Elements)) {
+			indexes[idx] = int64(len(arr.Elements))
+		}
 	}
 
-	// Make sure all other args are int values
-	for _, arg := range args[1:] {
+	start, end := indexes[0], indexes[1]
+	if start > end {
+		return newErrorOfKind(object.INDEX_ERROR, "slice start index %d is bigger than the end index %d", start, end)
+	}
+
+	// a new array, so changing it doesn't change the original one
+	elements := make([]object.Object, end-start)
+	copy(elements, arr.Elements[start:end])
+
+	return &object.Array{Elements: elements}
+}
+
+/**
+slice(str, start, end) works with characters, not bytes: slice("héllo", 1, 3) == "él"
+- end is optional, defaults to the end of the string
+- indexes past the end of the string get clamped to its length
+**/
+func sliceString(args []object.Object) object.Object {
+	if len(args) < 2 || len(args) > 3 {
+		return newErrorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments passed to slice. Got %d wanted 2 or 3", len(args))
+	}
+
+	chars := []rune(args[0].(*object.String).Value)
+	indexes := []int64{0, int64(len(chars))}
+
+	for idx, arg := range args[1:] {
 		obj, isInt := arg.(*object.Integer)
 
 		if !isInt {
-			return newError("expected an integer, got a type of %T instead", arg.Type())
+			return newErrorOfKind(object.TYPE_ERROR, "expected an integer, got a type of %s instead", arg.Type())
 		}
 
-		if isInt {
-			if obj.Value < 0 {
-				return newError("Negative indexes not supported (yet), recieved value of %d", obj.Value)
-			}
+		if obj.Value < 0 {
+			return newErrorOfKind(object.ARGUMENT_ERROR, "Negative indexes not supported (yet), recieved value of %d", obj.Value)
 		}
 
+		indexes[idx] = obj.Value
+		if indexes[idx] > int64(len(chars)) {
+			indexes[idx] = int64(len(chars))
+		}
 	}
 
-	firstIdx := args[1].(*object.Integer).Value
+	start, end := indexes[0], indexes[1]
+	if start > end {
+		return newErrorOfKind(object.INDEX_ERROR, "slice start index %d is bigger than the end index %d", start, end)
+	}
+
+	return &object.String{Value: string(chars[start:end])}
+}
+
+/**
+bytes(str) returns the UTF-8 bytes of the string as an array of integers, for byte level work:
+bytes("hé") == [104, 195, 169]
+**/
+func __bytes__(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newErrorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments. got %d, wanted 1", len(args))
+	}
+
+	str, ok := args[0].(*object.String)
+	if !ok {
+		return newErrorOfKind(object.TYPE_ERROR, "argument to `bytes` must be STRING, got %s", args[0].Type())
+	}
+
+	elements := make([]object.Object, len(str.Value))
+	for idx := 0; idx < len(str.Value); idx++ {
+		elements[idx] = &object.Integer{Value: int64(str.Value[idx])}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+/**
+range(end), range(start, end), range(start, end, step)
+Numbers from start (0 by default) up to end (excluded), step (1 by default) at a time.
+A negative step counts down: range(3, 0, -1) => 3, 2, 1
+**/
+func __range__(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 3 {
+		return newErrorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments passed to range. Got %d wanted 1 to 3", len(args))
+	}
+
+	values := []int64{}
+	for _, arg := range args {
+		obj, isInt := arg.(*object.Integer)
+		if !isInt {
+			return newErrorOfKind(object.TYPE_ERROR, "arguments to `range` must be INTEGER, got %s", arg.Type())
+		}
+		values = append(values, obj.Value)
+	}
 
-	// two index values passed
-	if len(args) == 3 {
-		secondIdx := args[2].(*object.Integer).Value
-		arrCopy.Elements = arrCopy.Elements[firstIdx:secondIdx]
+	r := &object.Range{Start: 0, Step: 1}
 
-		return arrCopy
+	switch len(values) {
+	case 1:
+		r.End = values[0]
+	case 2:
+		r.Start, r.End = values[0], values[1]
+	case 3:
+		r.Start, r.End, r.Step = values[0], values[1], values[2]
 	}
 
-	//only one index value passed
-	arrCopy.Elements = arrCopy.Elements[firstIdx:]
+	if r.Step == 0 {
+		return newErrorOfKind(object.ARGUMENT_ERROR, "range step can't be 0")
+	}
 
-	return arrCopy
+	return r
+}
+
+/**
+freeze(value): makes an array or hash read-only, along with every array / hash inside of it.
+Index assignments, pop(), shift() and delete() on it are TypeErrors from then on.
+Returns the value itself, so it can wrap a literal: const config = freeze({"retries": 3})
+**/
+func __freeze__(ctx *object.Context, args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return newErrorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments passed to freeze. Got %d wanted 1", len(args))
+	}
+
+	freezeObject(args[0])
+
+	return args[0]
+}
+
+func freezeObject(obj object.Object) {
+	switch obj := obj.(type) {
+	// already frozen ones are skipped, so an array that contains itself doesn't loop forever
+	case *object.Array:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for _, element := range obj.Elements {
+			freezeObject(element)
+		}
+	case *object.Hash:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for _, pair := range obj.Pairs() {
+			freezeObject(pair.Value)
+		}
+	}
 }
 
 func checkForHashErrors(formatter ErrorFormatter) object.Object {
 	args, functionName, argumentsExpected := formatter.Arguments, formatter.FuncName, formatter.ArgumentsExpected
 
 	if len(args) < argumentsExpected {
-		return newError("wrong number of arguments, got %d wanted %d", len(args), argumentsExpected)
+		return newErrorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments, got %d wanted %d", len(args), argumentsExpected)
 	}
 
 	if !isHash(args[0]) {
-		return newError("argument to `%s` must be HASH, got %s", functionName, args[0].Type())
+		return newErrorOfKind(object.TYPE_ERROR, "argument to `%s` must be HASH, got %s", functionName, args[0].Type())
 	}
 
 	return NULL
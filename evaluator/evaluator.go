@@ -3,20 +3,49 @@ package evaluator
 import (
 	"boar/ast"
 	"boar/object"
+	"boar/resolver"
+	"boar/token"
+	"bytes"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 )
 
 var (
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
-	NULL  = &object.Null{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
+// How many function calls can be in progress at the same time before we give up with a RecursionError
+const MAX_CALL_DEPTH = 10000
+
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := evalNode(node, env)
+
+	// Every expression has a value, even when its last statement doesn't produce one: fn() { let x = 1 }(), if (true) {}
+	if _, isExpression := node.(ast.Expression); isExpression && result == nil {
+		return NULL
+	}
+
+	// Errors get tagged with the position of the innermost node that produced them,
+	// outer nodes just pass them along.
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() && node != nil {
+		err.Pos = node.Pos()
+	}
+
+	return result
+}
+
+func evalNode(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	//statements
 	case *ast.Program:
-		return evalProgram(node.Statements, env)
+		return evalProgram(node, env)
 
 		// a single statement
 	case *ast.ExpressionStatement:
@@ -26,6 +55,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.LetStatement:
 		// evaluate the value
 		val := Eval(node.Value, env)
@@ -35,7 +67,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 
 		// assign the value to the identifier: let x = 0
-		env.Set(node.Name.Value, val)
+		if result := declareVariable(env, node, val); isError(result) {
+			return result
+		}
 
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
@@ -66,11 +100,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 
 		if isError(right) {
-			return left
+			return right
 		}
 
 		return evalInfixExpression(node.Operator, left, right)
 
+	case *ast.LogicalExpression:
+		return evalLogicalExpression(node, env)
+
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 
@@ -78,22 +115,29 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		params := node.Parameters
 		body := node.Body
 		// note: the env set here is the env/scope the function was defined in
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Name: node.Name, Parameters: params, Defaults: node.Defaults, Rest: node.Rest, Env: env, Body: body, Locals: node.Locals}
+
+	// already defined when the enclosing block was entered, see hoistFunctions
+	case *ast.FunctionStatement:
+		return nil
 
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
-		if isError(function) {
-			return function
-		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
-			return args[0]
-		}
+		return evalCallExpression(node, env)
 
-		return applyFunction(function, args)
+	case *ast.TryStatement:
+		return evalTryStatement(node, env)
+
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return newThrownError(val)
 
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
 
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
@@ -105,6 +149,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
+
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -128,6 +175,14 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 		return evalIndexExpression(left, index)
 
+	case *ast.PropertyExpression:
+		left := Eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+
+		return evalPropertyExpression(left, node.Property.Value)
+
 	case *ast.IndexAssignment:
 		// left -> The expression using the index operator: hash[a], arr[2+2], etc
 		left := Eval(node.Left, env)
@@ -153,8 +208,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalHashLiteral(node, env)
 
 	case *ast.InternalFunctionCall:
-		// someArr, someHash
-		caller_ident := Eval(node.CallerIdentifier, env)
+		// someArr, someHash, [1, 2, 3], arr.map(f)
+		caller_ident := Eval(node.Caller, env)
 
 		if isError(caller_ident) {
 			return caller_ident
@@ -163,29 +218,30 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		func_ident := Eval(node.FunctionIdentifier, env)
 
 		if isError(func_ident) {
-			return caller_ident
+			return func_ident
 		}
 		// (1,2,3), ("a", "b", "c"), etc
 		args := evalExpressions(node.Arguments, env)
 
-		for _, arg := range args {
-			if isError(arg) {
-				return nil
-			}
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
 		}
 		//  ( someArr/someHash, (1,2,3) )
 		newArgs := append([]object.Object{caller_ident}, args...)
 
 		// call the function as usual builtInFunc(objectIdentifier, args)
-		return applyFunction(func_ident, newArgs)
+		return traceCall(applyFunction(env.Context(), func_ident, newArgs), node.FunctionIdentifier, func_ident, node.Pos(), newArgs, nil)
 
 	case *ast.AssignmentExpression:
 		// x, y, someIdentifier
-		// lets make sure this value exists (either in this scope or any existing outer scopes)
+		// lets make sure this variable exists (either in this scope or any existing outer scopes)
 
-		_, exists := env.Get(node.Name.Value)
+		_, exists := getVariable(env, node.Name)
+		if !exists && node.Name.Binding.Kind == ast.LOCAL {
+			return usedBeforeDeclaration(node.Name)
+		}
 		if !exists {
-			return newError(`Identifier "%s" not found`, node.Name.Value)
+			return newErrorOfKind(object.NAME_ERROR, `Identifier "%s" not found`, node.Name.Value)
 		}
 
 		val := Eval(node.Value, env)
@@ -194,41 +250,48 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return val
 		}
 
-		env.Set(node.Name.Value, val)
+		// like index assignments, x = 5 evaluates to the assigned value
+		return assignVariable(env, node.Name, val)
 
 	case *ast.ForLoopStatement:
-		// Lets set the counter var in the env
-		counterVar := Eval(node.CounterVar, env)
-
-		if isError(counterVar) {
-			return counterVar
-		}
-
-		// Now lets make sure it actually got set / exists
-		counterVar, exists := env.Get(node.CounterVar.Name.Value)
+		return applyForLoop(node, env)
 
-		if !exists {
-			return counterVar
-		}
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
 
-		// Lets make sure we have a valid for loop condition
-		loopCondition := Eval(node.LoopCondition, env)
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
 
-		if isError(loopCondition) {
-			return loopCondition
-		}
+	case *ast.BreakStatement:
+		return BREAK
 
-		// Now run the for loop.
-		return applyForLoop(node, env)
+	case *ast.ContinueStatement:
+		return CONTINUE
 	}
 
 	return nil
 }
 
-func evalProgram(stmts []ast.Statement, env *object.Environment) object.Object {
-	var result object.Object
+/**
+No script should be able to crash the process, every problem has to come back as an error object.
+The recover is just the last line of defense: a Go panic coming from here is a bug in the interpreter,
+but the script still gets an InternalError instead of taking the whole process (the REPL, an embedding program) down.
+**/
+func evalProgram(program *ast.Program, env *object.Environment) (result object.Object) {
+	defer recoverInternalError(&result)
+
+	// programs coming straight from the parser, the REPL and boar.Interpreter resolve them before calling Eval
+	if !program.Resolved {
+		if diagnostics := resolver.Resolve(program, env); len(diagnostics) != 0 {
+			err := newErrorOfKind(object.NAME_ERROR, diagnostics[0].Message)
+			err.Pos = diagnostics[0].Span.Start
+			return err
+		}
+	}
 
-	for _, statement := range stmts {
+	hoistFunctions(program.Statements, env)
+
+	for _, statement := range program.Statements {
 		result = Eval(statement, env)
 
 		switch result := result.(type) {
@@ -259,7 +322,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -281,8 +344,12 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+	if float, ok := right.(*object.Float); ok {
+		return &object.Float{Value: -float.Value}
+	}
+
 	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
 	}
 
 	//extract value from *object.Integer via type assertion
@@ -295,16 +362,19 @@ func evalInfixExpression(operator string, left object.Object, right object.Objec
 	switch {
 	case bothAreIntegers(left, right):
 		return evalIntegerInfixExpression(operator, left, right)
+	// at least one of them is a float: 1.5 + 1.5, 1 + 1.5, 1.5 * 2
+	case bothAreNumbers(left, right):
+		return evalFloatInfixExpression(operator, left, right)
 	case operator == "==":
 		return nativeBoolToBooleanObject(left == right)
 	case operator == "!=":
 		return nativeBoolToBooleanObject(left != right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 	case bothAreStrings(left, right):
 		return evalStringInfixExpression(operator, left, right)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -312,6 +382,10 @@ func bothAreIntegers(a, b object.Object) bool {
 	return isInteger(a) && isInteger(b)
 }
 
+func bothAreNumbers(a, b object.Object) bool {
+	return isNumber(a) && isNumber(b)
+}
+
 func bothAreStrings(a, b object.Object) bool {
 	return isString(a) && isString(b)
 }
@@ -329,17 +403,158 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newErrorOfKind(object.ZERO_DIVISION_ERROR, "division by zero: %d / %d", leftVal, rightVal)
+		}
 		return &object.Integer{Value: leftVal / rightVal}
+	case "%":
+		if rightVal == 0 {
+			return newErrorOfKind(object.ZERO_DIVISION_ERROR, "division by zero: %d %% %d", leftVal, rightVal)
+		}
+		return &object.Integer{Value: leftVal % rightVal}
+	case "**":
+		// 2 ** -1 can't be an integer
+		if rightVal < 0 {
+			return &object.Float{Value: math.Pow(float64(leftVal), float64(rightVal))}
+		}
+		power, ok := integerPower(leftVal, rightVal)
+		if !ok {
+			return newErrorOfKind(object.RUNTIME_ERROR, "integer overflow: %d ** %d", leftVal, rightVal)
+		}
+		return &object.Integer{Value: power}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+/**
+Mixed int / float arithmetic: the integer gets converted into a float and the result is always a float.
+1 + 1.5 => 2.5, 2 * 1.5 => 3.0, 1 == 1.0 => true
+**/
+func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newErrorOfKind(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// base ** exp for a non negative exp (exponentiation by squaring), false when the result doesn't fit in an int64
+func integerPower(base, exp int64) (int64, bool) {
+	result := int64(1)
+	ok := true
+
+	for exp > 0 {
+		if exp&1 == 1 {
+			if result, ok = multiplyIntegers(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		// base only gets squared when it's still needed, 2 ** 62 is fine even though 2 ** 64 isn't
+		if exp > 0 {
+			if base, ok = multiplyIntegers(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+
+	return result, true
+}
+
+// a * b, false when it wraps around
+func multiplyIntegers(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	product := a * b
+	if product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+
+	return product, true
+}
+
+/**
+&& and || short-circuit, the right side is only evaluated when the left side doesn't decide the result:
+- false && crash() => false, crash() never gets called
+- true || crash() => true
+
+Like the if expression, they work with truthy / falsy values and give back the operand that decided the result:
+- null || "default" => "default"
+- 1 && 2 => 2
+**/
+func evalLogicalExpression(node *ast.LogicalExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+
+	if isError(left) {
+		return left
+	}
+
+	switch node.Operator {
+	case "&&":
+		if !isTruthy(left) {
+			return left
+		}
+	case "||":
+		if isTruthy(left) {
+			return left
+		}
+	default:
+		return newErrorOfKind(object.TYPE_ERROR, "unknown operator: %s %s", left.Type(), node.Operator)
+	}
+
+	return Eval(node.Right, env)
+}
+
+// Returns the value of an *object.Integer or *object.Float as a float64
+func toFloat(o object.Object) float64 {
+	switch o := o.(type) {
+	case *object.Integer:
+		return float64(o.Value)
+	case *object.Float:
+		return o.Value
+	default:
+		return 0
 	}
 }
 
@@ -359,6 +574,82 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Obje
 	}
 }
 
+// Evaluates the body of the first arm that matches the subject
+func evalMatchExpression(me *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(me.Subject, env)
+
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		matched, err := matchesArm(arm, subject, env)
+
+		if err != nil {
+			return err
+		}
+
+		if matched {
+			return Eval(arm.Body, env)
+		}
+	}
+
+	return newErrorOfKind(object.MATCH_ERROR, "no match arm for value: %s", subject.Inspect())
+}
+
+func matchesArm(arm *ast.MatchArm, subject object.Object, env *object.Environment) (bool, object.Object) {
+	matched := false
+
+	for _, pattern := range arm.Patterns {
+		if ast.IsWildcard(pattern) {
+			matched = true
+			break
+		}
+
+		value := Eval(pattern, env)
+
+		if isError(value) {
+			return false, value
+		}
+
+		if patternEquals(subject, value) {
+			matched = true
+			break
+		}
+	}
+
+	if !matched || arm.Guard == nil {
+		return matched, nil
+	}
+
+	guard := Eval(arm.Guard, env)
+
+	if isError(guard) {
+		return false, guard
+	}
+
+	return isTruthy(guard), nil
+}
+
+/**
+Patterns are literals, so comparing them by hash key is enough:
+- 1 matches 1 and 1.0, "a" matches "a", true matches true.
+- Values of different types never match (1 doesn't match "1" or true).
+**/
+func patternEquals(subject, pattern object.Object) bool {
+	a, ok := subject.(object.Hashable)
+	if !ok {
+		return false
+	}
+
+	b, ok := pattern.(object.Hashable)
+	if !ok {
+		return false
+	}
+
+	return a.HashKey() == b.HashKey()
+}
+
 func isTruthy(obj object.Object) bool {
 	switch obj {
 	case NULL:
@@ -375,6 +666,13 @@ func isTruthy(obj object.Object) bool {
 func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
+	// the block declares variables of its own, see resolver.resolveScopedBlock
+	if block.Locals > 0 {
+		env = object.NewScope(env, block.Locals)
+	}
+
+	hoistFunctions(block.Statements, env)
+
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 		// if the result is an *object.ReturnValue, return it without unwrapping its .Value
@@ -382,7 +680,7 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 		if result != nil {
 			rt := result.Type()
 
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ || rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
@@ -391,10 +689,158 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	return result
 }
 
+/**
+Defines every fn name() { ... } declaration in the block before any of its statements run.
+- fn declarations can be called before the line they're declared on.
+- Functions declared in the same block can call each other, since they all close over the same env.
+**/
+func hoistFunctions(stmts []ast.Statement, env *object.Environment) {
+	for _, statement := range stmts {
+		if decl, ok := statement.(*ast.FunctionStatement); ok {
+			setVariable(env, decl.Name, Eval(decl.Function, env))
+		}
+	}
+}
+
+/**
+try { ... } catch (e) { ... } finally { ... }
+
+- An error coming out of the try block (thrown or a runtime error from anywhere, builtins included) runs the catch block,
+the catch block gets the error as a hash: e.message, e.kind, e.line, e.column, etc (see errorToHash).
+- The finally block always runs. If it throws, returns, breaks or continues that wins over the result of try / catch.
+- return, break and continue inside of try / catch aren't errors, they pass through (after running finally).
+**/
+func evalTryStatement(ts *ast.TryStatement, env *object.Environment) object.Object {
+	result := Eval(ts.Block, env)
+
+	if err, ok := result.(*object.Error); ok && ts.Catch != nil {
+		catchEnv := object.NewScope(env, ts.CatchLocals)
+
+		if ts.CatchParam != nil {
+			setVariable(catchEnv, ts.CatchParam, errorToHash(err))
+		}
+
+		result = Eval(ts.Catch, catchEnv)
+	}
+
+	if ts.Finally != nil {
+		finally := Eval(ts.Finally, env)
+
+		switch typeOf(finally) {
+		case object.ERROR_OBJ, object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+			return finally
+		}
+	}
+
+	return result
+}
+
+/**
+throw <value>
+
+- throw "message" => kind "Error"
+- throw {"kind": "ValidationError", "message": "bad record", "id": 5} => the message and kind come from the hash,
+the catch block gets the same fields back (e.id) next to the usual ones.
+**/
+func newThrownError(value object.Object) *object.Error {
+	err := &object.Error{Message: value.Inspect(), Kind: object.THROWN_ERROR, Value: value}
+
+	if hash, ok := value.(*object.Hash); ok {
+		if message, ok := hashStringField(hash, "message"); ok {
+			err.Message = message
+		}
+
+		if kind, ok := hashStringField(hash, "kind"); ok {
+			err.Kind = kind
+		}
+	}
+
+	return err
+}
+
+func hashStringField(hash *object.Hash, name string) (string, bool) {
+	pair, ok := hash.Get((&object.String{Value: name}).HashKey())
+	if !ok {
+		return "", false
+	}
+
+	str, ok := pair.Value.(*object.String)
+	if !ok {
+		return "", false
+	}
+
+	return str.Value, true
+}
+
+/**
+The value a catch block gets for an error:
+{"message": ..., "kind": ..., "line": ..., "column": ..., "file": ..., "function": ..., "value": ..., "stack": [...]}
+
+- "value" is whatever was thrown (null for runtime errors).
+- When a hash was thrown its own fields are kept, the fields above only fill in the missing ones.
+That keeps the original location when an error gets caught and thrown again (throw e).
+**/
+func errorToHash(err *object.Error) *object.Hash {
+	hash := &object.Hash{}
+
+	if thrown, ok := err.Value.(*object.Hash); ok {
+		for _, pair := range thrown.Pairs() {
+			hash.Set(pair.Key.(object.Hashable).HashKey(), pair)
+		}
+	}
+
+	value := err.Value
+	if value == nil {
+		value = NULL
+	}
+
+	fields := []struct {
+		name  string
+		value object.Object
+	}{
+		{"message", &object.String{Value: err.Message}},
+		{"kind", &object.String{Value: err.KindName()}},
+		{"line", &object.Integer{Value: int64(err.Pos.Line)}},
+		{"column", &object.Integer{Value: int64(err.Pos.Column)}},
+		{"file", &object.String{Value: err.Pos.File}},
+		{"function", &object.String{Value: err.Function}},
+		{"value", value},
+		{"stack", stackToArray(err.Trace)},
+	}
+
+	for _, field := range fields {
+		key := &object.String{Value: field.name}
+
+		if _, exists := hash.Get(key.HashKey()); !exists {
+			hash.Set(key.HashKey(), object.HashPair{Key: key, Value: field.value})
+		}
+	}
+
+	return hash
+}
+
+// ["at inner(1) (2:5)", "at outer() (4:1)"]
+func stackToArray(trace []object.StackFrame) *object.Array {
+	frames := &object.Array{Elements: []object.Object{}}
+
+	for _, frame := range trace {
+		frames.Elements = append(frames.Elements, &object.String{Value: frame.String()})
+	}
+
+	return frames
+}
+
 func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// Same as newError, for errors that scripts might want to tell apart: object.TYPE_ERROR, object.NAME_ERROR, etc
+func newErrorOfKind(kind string, format string, a ...interface{}) *object.Error {
+	err := newError(format, a...)
+	err.Kind = kind
+	return err
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ERROR_OBJ
@@ -404,11 +850,83 @@ func isError(obj object.Object) bool {
 
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	// check if value exists in env
-	if val, ok := env.Get(node.Value); ok {
+	if val, ok := getVariable(env, node); ok {
 		return val
 	}
 
-	return newError("identifier not found: " + node.Value)
+	// the slot exists but its let didn't run yet, see resolver
+	if node.Binding.Kind == ast.LOCAL {
+		return usedBeforeDeclaration(node)
+	}
+
+	return newErrorOfKind(object.NAME_ERROR, "identifier not found: "+node.Value)
+}
+
+/**
+Reads the variable an identifier refers to, where it lives was worked out by the resolver:
+- locals are a slot in one of the enclosing scopes
+- globals are looked up by name in the global environment
+
+Identifiers the resolver never saw (nodes evaluated on their own) are looked up by name in every scope.
+A local that hasn't been given a value yet is not found: fn() { let h = fn() { x }; h(); let x = 1 }
+**/
+func getVariable(env *object.Environment, ident *ast.Identifier) (object.Object, bool) {
+	switch ident.Binding.Kind {
+	case ast.LOCAL:
+		return env.GetAt(ident.Binding.Depth, ident.Binding.Slot)
+	case ast.GLOBAL:
+		return env.GetGlobal(ident.Value)
+	default:
+		return env.Get(ident.Value)
+	}
+}
+
+// Stores val in the variable an identifier refers to (let, parameters), see getVariable
+func setVariable(env *object.Environment, ident *ast.Identifier, val object.Object) object.Object {
+	switch ident.Binding.Kind {
+	case ast.LOCAL:
+		return env.SetAt(ident.Binding.Depth, ident.Binding.Slot, val)
+	case ast.GLOBAL:
+		return env.SetGlobal(ident.Value, val)
+	default:
+		return env.Set(ident.Value, val)
+	}
+}
+
+// let x = val / const x = val
+func declareVariable(env *object.Environment, let *ast.LetStatement, val object.Object) object.Object {
+	if !let.Const {
+		return setVariable(env, let.Name, val)
+	}
+
+	switch let.Name.Binding.Kind {
+	case ast.LOCAL:
+		return env.SetConstAt(let.Name.Binding.Depth, let.Name.Binding.Slot, let.Name.Value, val)
+	case ast.GLOBAL:
+		return env.SetGlobalConst(let.Name.Value, val)
+	default:
+		return env.SetConst(let.Name.Value, val)
+	}
+}
+
+/**
+x = val, updates the variable in the scope it was declared in (never the current one if that's not where it lives),
+so closures can change the variables they closed over: counters, accumulators, caches, etc.
+**/
+func assignVariable(env *object.Environment, ident *ast.Identifier, val object.Object) object.Object {
+	if ident.Binding.Kind != ast.UNRESOLVED {
+		return setVariable(env, ident, val)
+	}
+
+	if _, ok := env.Assign(ident.Value, val); !ok {
+		return newErrorOfKind(object.NAME_ERROR, `Identifier "%s" not found`, ident.Value)
+	}
+
+	return val
+}
+
+func usedBeforeDeclaration(ident *ast.Identifier) *object.Error {
+	return newErrorOfKind(object.NAME_ERROR, "%s used before its declaration", ident.Value)
 }
 
 // evaluate expressions (left to right)
@@ -426,57 +944,265 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func evalCallExpression(node *ast.CallExpression, env *object.Environment) object.Object {
+	function := Eval(node.Function, env)
+	if isError(function) {
+		return function
+	}
+	args := evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+
+	var named map[string]object.Object
+	if len(node.NamedArguments) > 0 {
+		named = map[string]object.Object{}
+	}
+
+	for _, arg := range node.NamedArguments {
+		value := Eval(arg.Value, env)
+		if isError(value) {
+			return value
+		}
+		named[arg.Name.Value] = value
+	}
+
+	result := applyFunctionWithNamedArgs(env.Context(), function, args, named)
+
+	return traceCall(result, node.Function, function, node.Pos(), args, named)
+}
+
+/**
+Adds a frame to the stack trace of an error coming out of a call to a user function.
+
+The trace gets built while the error travels back up through the calls,
+so by the time it reaches the top level it holds every call that was active when it happened, innermost first.
+Errors coming straight out of builtins don't get a frame, their position already points at the call.
+**/
+func traceCall(result object.Object, callee ast.Expression, fn object.Object, pos token.Position, args []object.Object, named map[string]object.Object) object.Object {
+	err, isErr := result.(*object.Error)
+	function, isFunction := fn.(*object.Function)
+
+	if !isErr || !isFunction {
+		return result
+	}
+
+	name := function.DisplayName()
+	// let add = fn(x) { ... } doesn't name the function, the variable it's called through is the next best thing
+	if ident, ok := callee.(*ast.Identifier); ok && function.Name == "" {
+		name = ident.Value
+
+		// the error happened right in this call, it's the innermost function with a name
+		if len(err.Trace) == 0 && err.Function == "" {
+			err.Function = name
+		}
+	}
+
+	err.Trace = append(err.Trace, object.StackFrame{Function: name, Pos: pos, Args: summarizeArguments(args, named)})
+
+	return err
+}
+
+// 1, "a", [1, 2, 3], y: fn
+func summarizeArguments(args []object.Object, named map[string]object.Object) string {
+	summary := []string{}
+
+	for _, arg := range args {
+		summary = append(summary, summarizeValue(arg))
+	}
+
+	names := []string{}
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		summary = append(summary, name+": "+summarizeValue(named[name]))
+	}
+
+	return strings.Join(summary, ", ")
+}
+
+// Short, single line version of a value for stack traces
+func summarizeValue(obj object.Object) string {
+	const MAX_LENGTH = 20
+
+	var str string
+
+	switch obj := obj.(type) {
+	case nil:
+		return "null"
+	case *object.Function:
+		if obj.Name == "" {
+			return "fn"
+		}
+		return "fn " + obj.Name
+	case *object.String:
+		str = strconv.Quote(obj.Value)
+	default:
+		str = obj.Inspect()
+	}
+
+	if runes := []rune(str); len(runes) > MAX_LENGTH {
+		str = string(runes[:MAX_LENGTH-3]) + "..."
+	}
+
+	return str
+}
+
+// Deferred by the entry points of the evaluator, turns a Go panic into an InternalError result
+func recoverInternalError(result *object.Object) {
+	if r := recover(); r != nil {
+		*result = newErrorOfKind(object.INTERNAL_ERROR, "internal error: %v", r)
+	}
+}
+
+/**
+Calls a boar function (user defined or builtin) with arguments that are already evaluated,
+for Go code calling into a program: boar.Interpreter.Call
+env is the environment of the program, builtins get its context.
+**/
+func CallFunction(fn object.Object, args []object.Object, env *object.Environment) (result object.Object) {
+	defer recoverInternalError(&result)
+
+	return applyFunction(env.Context(), fn, args)
+}
+
+func applyFunction(ctx *object.Context, fn object.Object, args []object.Object) object.Object {
+	return applyFunctionWithNamedArgs(ctx, fn, args, nil)
+}
+
+// f(1, 2, name: 3) => args: [1, 2], named: {"name": 3}
+// ctx is the context of the caller, builtins get it passed along
+func applyFunctionWithNamedArgs(ctx *object.Context, fn object.Object, args []object.Object, named map[string]object.Object) object.Object {
 
 	switch fn := fn.(type) {
 
 	// check if its a regular function
 	case *object.Function:
-		arr, isArray := args[0].(*object.Array)
-		// Possibly a #map call
-		if isArray && len(args) == 1 {
-			return applyMapCall(arr, fn)
+		if !fn.Env.Step() {
+			return stepLimitError(fn.Env)
 		}
+		if !fn.Env.EnterCall(MAX_CALL_DEPTH) {
+			return newErrorOfKind(object.RECURSION_ERROR, "maximum call depth of %d exceeded", MAX_CALL_DEPTH)
+		}
+		defer fn.Env.ExitCall()
+
 		// create the inner function scope
-		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv, err := extendFunctionEnv(fn, args, named)
+		if err != nil {
+			return err
+		}
 		//evalute the function body with the inner scope
 		evaluated := Eval(fn.Body, extendedEnv)
+
+		// remember the innermost named function the error came from
+		if err, ok := evaluated.(*object.Error); ok && err.Function == "" {
+			err.Function = fn.Name
+		}
+
 		// if the object has a return value, return that value
 		// else, return the object.
 		return unwrapReturnValue(evaluated)
 
 	// return the built in function, pass args
 	case *object.Builtin:
-		return fn.Fn(args...)
+		if len(named) > 0 {
+			return newErrorOfKind(object.ARGUMENT_ERROR, "builtin functions don't take named arguments")
+		}
+		return fn.Fn(ctx, args...)
 
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "not a function: %s", fn.Type())
 	}
 }
 
-func applyMapCall(arr *object.Array, fn *object.Function) object.Object {
+// map(arr, fn): calls fn with every element, stops at the first error
+func applyMapCall(ctx *object.Context, arr *object.Array, fn *object.Function) object.Object {
 	res := &object.Array{}
 	for _, val := range arr.Elements {
-		args := []object.Object{val}
-		// create an inner function scope for each function call
-		extendedEnv := extendFunctionEnv(fn, args)
-		// Evaluate
-		evaluated := Eval(fn.Body, extendedEnv)
+		evaluated := applyFunction(ctx, fn, []object.Object{val})
+		if isError(evaluated) {
+			return evaluated
+		}
 		// Add result to the array
-		res.Elements = append(res.Elements, unwrapReturnValue(evaluated))
+		res.Elements = append(res.Elements, evaluated)
 	}
 	return res
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
+/**
+Binds the arguments of a call to the function's parameters in a new inner scope.
+
+Each parameter gets (in this order):
+- the positional argument in its position
+- the named argument with its name
+- its default value, evaluated in the inner scope so it can use the parameters before it: fn(x, y = x * 2)
+
+Positional arguments that don't have a parameter go into the rest parameter (as an array).
+Missing arguments, extra arguments and unknown names are errors.
+**/
+func extendFunctionEnv(fn *object.Function, args []object.Object, named map[string]object.Object) (*object.Environment, *object.Error) {
 	// create inner function scope
-	env := object.NewEnclosedEnvironment(fn.Env)
+	env := object.NewScope(fn.Env, fn.Locals)
+
+	if len(args) > len(fn.Parameters) && fn.Rest == nil {
+		return nil, wrongNumberOfArguments(fn, len(args)+len(named))
+	}
+
+	for name := range named {
+		if !fn.HasParameter(name) {
+			return nil, newErrorOfKind(object.ARGUMENT_ERROR, "%s has no parameter named %s", fn.DisplayName(), name)
+		}
+	}
+
 	// bind the arguments used in the function call to the parameter names in that inner function scope
 	for idx, param := range fn.Parameters {
-		env.Set(param.Value, args[idx])
+		namedValue, isNamed := named[param.Value]
+
+		switch {
+		case idx < len(args):
+			if isNamed {
+				return nil, newErrorOfKind(object.ARGUMENT_ERROR, "%s got more than one value for parameter %s", fn.DisplayName(), param.Value)
+			}
+			setVariable(env, param, args[idx])
+		case isNamed:
+			setVariable(env, param, namedValue)
+		case fn.Defaults[param.Value] != nil:
+			value := Eval(fn.Defaults[param.Value], env)
+			if err, ok := value.(*object.Error); ok {
+				return nil, err
+			}
+			setVariable(env, param, value)
+		default:
+			return nil, wrongNumberOfArguments(fn, len(args)+len(named))
+		}
+	}
+
+	if fn.Rest != nil {
+		rest := &object.Array{Elements: []object.Object{}}
+		if len(args) > len(fn.Parameters) {
+			rest.Elements = append(rest.Elements, args[len(fn.Parameters):]...)
+		}
+		setVariable(env, fn.Rest, rest)
+	}
+
+	return env, nil
+}
+
+func wrongNumberOfArguments(fn *object.Function, got int) *object.Error {
+	required := len(fn.Parameters) - len(fn.Defaults)
+
+	wanted := fmt.Sprintf("%d", required)
+	switch {
+	case fn.Rest != nil:
+		wanted = fmt.Sprintf("at least %d", required)
+	case required != len(fn.Parameters):
+		wanted = fmt.Sprintf("%d to %d", required, len(fn.Parameters))
 	}
 
-	return env
+	return newErrorOfKind(object.ARGUMENT_ERROR, "wrong number of arguments passed to %s. Got %d wanted %s", fn.DisplayName(), got, wanted)
 }
 
 /**
@@ -499,7 +1225,7 @@ func unwrapReturnValue(obj object.Object) object.Object {
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	// currently we only support concatenation: (string + string)
 	if operator != "+" {
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 
 	leftVal := left.(*object.String).Value
@@ -509,14 +1235,38 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 
 }
 
+// Joins the parts of an interpolated string, every value is converted into a string with Inspect()
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out bytes.Buffer
+
+	for _, part := range node.Parts {
+		value := Eval(part, env)
+
+		if isError(value) {
+			return value
+		}
+
+		// expressions that don't produce a value (let statements in a block, etc)
+		if value == nil {
+			value = NULL
+		}
+
+		out.WriteString(value.Inspect())
+	}
+
+	return &object.String{Value: out.String()}
+}
+
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case isArray(left) && isInteger(index):
 		return evalArrayIndexExpression(left, index)
+	case isString(left) && isInteger(index):
+		return evalStringIndexExpression(left, index)
 	case isHash(left):
 		return evalHashIndexExpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "index operator not supported: %s", left.Type())
 	}
 }
 
@@ -533,30 +1283,42 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
+// "héllo"[1] == "é", indexes count characters and not bytes
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	chars := []rune(str.(*object.String).Value)
+	idx := index.(*object.Integer).Value
+	max := int64(len(chars) - 1)
+
+	if idx < 0 || idx > max {
+		return NULL
+	}
+
+	return &object.String{Value: string(chars[idx])}
+}
+
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := &object.Hash{}
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, pairNode := range node.Pairs {
+		key := Eval(pairNode.Key, env)
 		if isError(key) {
 			return key
 		}
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newErrorOfKind(object.TYPE_ERROR, "unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(pairNode.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
@@ -565,10 +1327,10 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	key, ok := index.(object.Hashable)
 
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
 	}
 
-	pair, ok := hashObject.Pairs[key.HashKey()]
+	pair, ok := hashObject.Get(key.HashKey())
 
 	if !ok {
 		return NULL
@@ -577,7 +1339,20 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	return pair.Value
 }
 
+// hash.name is the same as hash["name"]
+func evalPropertyExpression(left object.Object, name string) object.Object {
+	if typeOf(left) != object.HASH_OBJ {
+		return newErrorOfKind(object.TYPE_ERROR, "property access not supported: %s.%s", typeOf(left), name)
+	}
+
+	return evalHashIndexExpression(left, &object.String{Value: name})
+}
+
 func evalIndexAssignment(indexable, index, value object.Object) object.Object {
+	if isFrozen(indexable) {
+		return frozenError(indexable)
+	}
+
 	hash, isHash := indexable.(*object.Hash)
 	array, isArray := indexable.(*object.Array)
 
@@ -589,19 +1364,17 @@ func evalIndexAssignment(indexable, index, value object.Object) object.Object {
 		return evalArrayIndexAssignment(array, index, value)
 	}
 
-	return newError("Invalid type passed, expected a type of Hash or Array, got %T instead", indexable.Type())
+	return newErrorOfKind(object.TYPE_ERROR, "Invalid type passed, expected a type of Hash or Array, got %s instead", typeOf(indexable))
 }
 
 func evalHashKeyAssignment(hash *object.Hash, index, value object.Object) object.Object {
 	key, ok := index.(object.Hashable)
 
 	if !ok {
-		return newError("unusable value as hash key: %s", index.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "unusable value as hash key: %s", index.Type())
 	}
 
-	hashed_key := key.HashKey()
-
-	hash.Pairs[hashed_key] = object.HashPair{Key: index, Value: value}
+	hash.Set(key.HashKey(), object.HashPair{Key: index, Value: value})
 
 	return value
 }
@@ -610,7 +1383,12 @@ func evalArrayIndexAssignment(array *object.Array, index, value object.Object) o
 	idx, ok := index.(*object.Integer)
 
 	if !ok {
-		return newError("Invalid index value passed, expected an integer, got: %T", index.Type())
+		return newErrorOfKind(object.TYPE_ERROR, "Invalid index value passed, expected an integer, got: %s", typeOf(index))
+	}
+
+	// arrays don't grow through assignments, push() is the way to add elements
+	if idx.Value < 0 || idx.Value >= int64(len(array.Elements)) {
+		return newErrorOfKind(object.INDEX_ERROR, "index %d out of range for an array of length %d", idx.Value, len(array.Elements))
 	}
 
 	array.Elements[idx.Value] = value
@@ -618,49 +1396,225 @@ func evalArrayIndexAssignment(array *object.Array, index, value object.Object) o
 	return value
 }
 
+/**
+for (let i = 0; i < n; i = i + 1) { body }
+
+The counter only exists inside of the loop. Every iteration gets its own copy of it,
+closures created in the body keep the value it had in their iteration:
+
+	let fns = []
+	for (let i = 0; i < 3; i = i + 1) { fns = push(fns, fn() { i }) }
+	fns[0]() // => 0
+**/
 func applyForLoop(forLoop *ast.ForLoopStatement, env *object.Environment) object.Object {
 	var result object.Object
 
-	//Evaluate the first loop condition
-	stopLoop := Eval(forLoop.LoopCondition, env)
-	loopCondition, ok := stopLoop.(*object.Boolean)
+	iterEnv := object.NewScope(env, forLoop.Locals)
+	if counter := Eval(forLoop.CounterVar, iterEnv); isError(counter) {
+		return counter
+	}
+
+	for {
+		// Evaluate the loop condition before every iteration
+		stopLoop := Eval(forLoop.LoopCondition, iterEnv)
+		if isError(stopLoop) {
+			return stopLoop
+		}
 
-	if !ok {
-		return newError("Invalid loop condition type: %T", loopCondition)
+		loopCondition, ok := stopLoop.(*object.Boolean)
+		if !ok {
+			return newError("Invalid loop condition type: %s", stopLoop.Type())
+		}
+
+		// the loop condition is false, we're done
+		if !loopCondition.Value {
+			return result
+		}
+
+		value, stop := evalLoopBody(forLoop.LoopBlock, iterEnv)
+		if stop {
+			return loopResult(value, result)
+		}
+		if value != nil {
+			result = value
+		}
+
+		// the next iteration starts with the counter of this one, then updates it
+		counter, _ := getVariable(iterEnv, forLoop.CounterVar.Name)
+		iterEnv = object.NewScope(env, forLoop.Locals)
+		setVariable(iterEnv, forLoop.CounterVar.Name, counter)
+
+		updateVal := Eval(forLoop.CounterUpdate.Value, iterEnv)
+		if isError(updateVal) {
+			return updateVal
+		}
+
+		if result := setVariable(iterEnv, forLoop.CounterVar.Name, updateVal); isError(result) {
+			return result
+		}
+	}
+}
+
+// while (condition) { body }, runs until the condition isn't truthy anymore
+func evalWhileStatement(node *ast.WhileStatement, env *object.Environment) object.Object {
+	var result object.Object
+
+	for {
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return result
+		}
+
+		value, stop := evalLoopBody(node.Body, env)
+		if stop {
+			return loopResult(value, result)
+		}
+		if value != nil {
+			result = value
+		}
 	}
+}
 
-	// While this loop condition is false
-	for loopCondition.Value {
-		result = Eval(forLoop.LoopBlock, env)
+/**
+for (x in arr) { ... }, for (k, v in hash) { ... }
 
-		updateVal := Eval(forLoop.CounterUpdate.Value, env)
+Works with anything that implements object.Iterable.
+Every iteration gets its own scope with the loop variables in it.
+**/
+func evalForInStatement(node *ast.ForInStatement, env *object.Environment) object.Object {
+	var result object.Object
 
-		env.Set(forLoop.CounterVar.Name.Value, updateVal)
+	collection := Eval(node.Iterable, env)
+	if isError(collection) {
+		return collection
+	}
 
-		//Continue evaluating the loop condition in the loop
-		loopCondition := Eval(forLoop.LoopCondition, env).(*object.Boolean)
+	iterable, ok := collection.(object.Iterable)
+	if !ok {
+		return newErrorOfKind(object.TYPE_ERROR, "cannot iterate over %s", typeOf(collection))
+	}
 
-		if !loopCondition.Value {
+	iterator := iterable.Iter()
+
+	for {
+		key, value, ok := iterator.Next()
+		if !ok {
 			return result
 		}
+
+		loopEnv := object.NewScope(env, node.Locals)
+
+		if len(node.Variables) == 2 {
+			setVariable(loopEnv, node.Variables[0], key)
+			setVariable(loopEnv, node.Variables[1], value)
+		} else if isHash(collection) {
+			setVariable(loopEnv, node.Variables[0], key)
+		} else {
+			setVariable(loopEnv, node.Variables[0], value)
+		}
+
+		bodyResult, stop := evalLoopBody(node.Body, loopEnv)
+		if stop {
+			return loopResult(bodyResult, result)
+		}
+		if bodyResult != nil {
+			result = bodyResult
+		}
 	}
-	return result
+}
+
+// The type of an object for error messages, statements like 'let' don't produce an object
+func typeOf(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL_OBJ
+	}
+
+	return obj.Type()
+}
+
+/**
+Evaluates one iteration of a loop body and handles the control flow signals coming out of it:
+- break: stop the loop
+- continue: go on with the next iteration
+- return values / errors: stop the loop and pass them along
+
+stop tells the loop whether it has to stop, value is the result of the body (nil for break / continue).
+**/
+func evalLoopBody(body *ast.BlockStatement, env *object.Environment) (value object.Object, stop bool) {
+	if !env.Step() {
+		return stepLimitError(env), true
+	}
+
+	result := Eval(body, env)
+
+	switch result.(type) {
+	case *object.ReturnValue, *object.Error:
+		return result, true
+	case *object.Break:
+		return nil, true
+	case *object.Continue:
+		return nil, false
+	}
+
+	return result, false
+}
+
+func stepLimitError(env *object.Environment) *object.Error {
+	return newErrorOfKind(object.STEP_LIMIT_ERROR, "step limit of %d exceeded", env.Context().MaxSteps)
+}
+
+// What a loop that was stopped evaluates to: the return value / error that stopped it, or the last result of its body
+func loopResult(value, lastResult object.Object) object.Object {
+	if value != nil {
+		return value
+	}
+
+	return lastResult
 }
 
 func isArray(o object.Object) bool {
-	return o.Type() == object.ARRAY_OBJ
+	return typeOf(o) == object.ARRAY_OBJ
 }
 
 func isInteger(o object.Object) bool {
-	return o.Type() == object.INTEGER_OBJ
+	return typeOf(o) == object.INTEGER_OBJ
+}
+
+func isFloat(o object.Object) bool {
+	return typeOf(o) == object.FLOAT_OBJ
+}
+
+// integers and floats
+func isNumber(o object.Object) bool {
+	return isInteger(o) || isFloat(o)
 }
 
 func isString(o object.Object) bool {
-	return o.Type() == object.STRING_OBJ
+	return typeOf(o) == object.STRING_OBJ
+}
+
+// Arrays and hashes passed to freeze()
+func isFrozen(o object.Object) bool {
+	switch o := o.(type) {
+	case *object.Array:
+		return o.Frozen
+	case *object.Hash:
+		return o.Frozen
+	}
+
+	return false
+}
+
+func frozenError(o object.Object) *object.Error {
+	return newErrorOfKind(object.TYPE_ERROR, "cannot modify a frozen %s", strings.ToLower(string(o.Type())))
 }
 
 func isHash(o object.Object) bool {
-	return o.Type() == object.HASH_OBJ
+	return typeOf(o) == object.HASH_OBJ
 }
 
 /**
@@ -4,6 +4,9 @@ import (
 	"boar/lexer"
 	"boar/object"
 	"boar/parser"
+	"bytes"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -27,6 +30,16 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"10 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"3 * 2 ** 2 % 5", 2},
+		{"2 ** 62", 4611686018427387904},
+		{"(0 - 2) ** 63", -9223372036854775808},
+		{"(0 - 1) ** 9223372036854775807", -1},
+		{"3 ** 39", 4052555153018976267},
 	}
 
 	for _, tt := range tests {
@@ -35,6 +48,160 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+// ** gives an error instead of wrapping around when the result doesn't fit in an integer
+func TestIntegerPowerOverflow(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"2 ** 100", "integer overflow: 2 ** 100"},
+		{"3 ** 64", "integer overflow: 3 ** 64"},
+		{"2 ** 63", "integer overflow: 2 ** 63"},
+		{"(0 - 3) ** 41", "integer overflow: -3 ** 41"},
+		{"9223372036854775807 ** 2", "integer overflow: 9223372036854775807 ** 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("expected an error for %q, got %s", tt.input, evaluated.Inspect())
+			continue
+		}
+
+		if err.Kind != object.RUNTIME_ERROR || err.Message != tt.expectedMessage {
+			t.Errorf("wrong error for %q, expected %s %q, got %s %q", tt.input, object.RUNTIME_ERROR, tt.expectedMessage, err.Kind, err.Message)
+		}
+	}
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5", 1.5},
+		{".5", 0.5},
+		{"-2.5", -2.5},
+		{"1e-3", 0.001},
+		{"1.5 + 1.5", 3.0},
+		{"1 + 1.5", 2.5},
+		{"1.5 * 2", 3.0},
+		{"10 / 4.0", 2.5},
+		{"5 - 0.5", 4.5},
+		{"(1 + 2 + 3) / 3.0", 2.0},
+		{"-.5 * 4", -2.0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestFloatComparisons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"1.5 < 2.5", true},
+		{"1.5 > 2", false},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"1.0 != 1", false},
+		{"0.1 + 0.2 == 0.3", false},
+		{"{1: 10}[1.0] == 10", true},
+		{"{2.0: 20}[2] == 20", true},
+		{"{1.5: 30}[1.5] == 30", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestNewOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 <= 1", false},
+		{"2 >= 1.5", true},
+		{"5.5 % 2", 1.5},
+		{"2 ** -1", 0.5},
+		{"2.0 ** 3", 8.0},
+		{"4 ** 0.5", 2.0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"!(true && false)", true},
+		{"1 && 2", 2},
+		{"false || 5", 5},
+		{"if (1 < 2 && 3 < 4) { 10 } else { 20 }", 10},
+		// the right side is never evaluated, so the division by zero doesn't blow up
+		{"false && 1 / 0", false},
+		{"true || 1 / 0", true},
+		{"let x = 1; false && (x = 2); x", 1},
+		{"let x = 1; true || (x = 2); x", 1},
+		{"let x = 1; true && (x = 2); x", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		}
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+
+	if !ok {
+		t.Errorf("object is not Float, got %T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value, got %f, wanted %f", result.Value, expected)
+		return false
+	}
+	return true
+}
+
 func loadBuiltInMethods(env *object.Environment) {
 	for key, value := range BUILTIN {
 		env.Set(key, value)
@@ -42,13 +209,22 @@ func loadBuiltInMethods(env *object.Environment) {
 }
 
 func testEval(input string) object.Object {
+	evaluated, _ := testEvalWithInput(input, "")
+	return evaluated
+}
+
+// Evaluates the input with stdin as the program's input, gives back the result and whatever the program printed
+func testEvalWithInput(input, stdin string) (object.Object, string) {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
 	env := object.NewEnvironment()
 	loadBuiltInMethods(env)
 
-	return Eval(program, env)
+	var out bytes.Buffer
+	env.SetContext(object.NewContext(strings.NewReader(stdin), &out, &out))
+
+	return Eval(program, env), out.String()
 }
 
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
@@ -66,6 +242,21 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+
+	if !ok {
+		t.Errorf("object is not String, got %T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value, got %q, wanted %q", result.Value, expected)
+		return false
+	}
+	return true
+}
+
 func testEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -154,6 +345,10 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else if (3 > 2) { 40 } else { 30 }", 40},
 	}
 
 	for _, tt := range tests {
@@ -168,6 +363,56 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"match (1) { 1 => 10, 2 => 20 }", 10},
+		{"match (2) { 1 => 10, 2 => 20, }", 20},
+		{"match (3) { 1, 2 => 10, 3, 4 => 20 }", 20},
+		{"match (1.0) { 1 => 10, _ => 20 }", 10},
+		{"match (-2) { -2 => 10, _ => 20 }", 10},
+		{"match (\"b\") { \"a\" => 10, \"b\" => 20 }", 20},
+		{"match (true) { false => 10, true => 20 }", 20},
+		// values of different types never match
+		{"match (1) { \"1\" => 10, true => 20, _ => 30 }", 30},
+		{"match (5) { _ => { let x = 2; x * 10 } }", 20},
+		{"let x = 50; match (x) { 1 => 10, _ if x > 100 => 20, _ if x > 10 => 30, _ => 40 }", 30},
+		{"let x = 2; match (x) { 1, 2 if x > 1 => 10, _ => 20 }", 10},
+		{"let x = 1; match (x) { 1, 2 if x > 1 => 10, _ => 20 }", 20},
+		// the first arm that matches wins
+		{"match (1) { _ => 10, 1 => 20 }", 10},
+		{"let f = fn(n) { match (n) { 0 => { return 100 }, _ => n } }; f(0) + f(1)", 101},
+		{"match (len(\"ab\")) { 2 => 10 } + 1", 11},
+		{"match (3) { 1 => 10, 2 => 20 }", "no match arm for value: 3"},
+		{"match ([1]) { 1 => 10 }", "no match arm for value: [1]"},
+		{"match (missing) { _ => 10 }", "identifier not found: missing"},
+		{"match (1) { _ if missing => 10 }", "identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+			if strings.HasPrefix(expected, "no match arm") && errObj.Kind != object.MATCH_ERROR {
+				t.Errorf("wrong error kind for %q. expected=%s, got=%s", tt.input, object.MATCH_ERROR, errObj.KindName())
+			}
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -246,6 +491,30 @@ func TestErrorHandling(t *testing.T) {
 			`{"name": "Boar"}[fn(x) { x }];`,
 			"unusable as hash key: FUNCTION",
 		},
+		{
+			"1.5 + true",
+			"type mismatch: FLOAT + BOOLEAN",
+		},
+		{
+			"10 / 0",
+			"division by zero: 10 / 0",
+		},
+		{
+			"10 % 0",
+			"division by zero: 10 % 0",
+		},
+		{
+			"true && missing",
+			"identifier not found: missing",
+		},
+		{
+			"true <= false",
+			"unknown operator: BOOLEAN <= BOOLEAN",
+		},
+		{
+			"-true + 1.5",
+			"unknown operator: -BOOLEAN",
+		},
 	}
 
 	for _, tt := range tests {
@@ -320,6 +589,144 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"fn double(x) { x * 2 }; double(5)", 10},
+		// declarations are hoisted to the top of their block
+		{"double(5); fn double(x) { x * 2 }", nil},
+		{"let a = double(5); fn double(x) { x * 2 }; a", 10},
+		{"fn fact(n) { if (n < 2) { 1 } else { n * fact(n - 1) } }; fact(5)", 120},
+		// mutual recursion
+		{`
+		fn isEven(n) { if (n == 0) { return 1 } isOdd(n - 1) }
+		fn isOdd(n) { if (n == 0) { return 0 } isEven(n - 1) }
+		isEven(10) + isOdd(7)
+		`, 2},
+		// hoisted inside of function bodies too
+		{"fn outer(x) { return inner(x) + 1; fn inner(y) { y * 10 } }; outer(2)", 21},
+		{"let x = 1; if (true) { let y = helper(); fn helper() { 41 }; x + y }", 42},
+		{"fn f() { 1 }; fn f() { 2 }; f()", 2},
+		{"fn add(a, b) { a + b }; add(1, missing)", "identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			if evaluated != nil && evaluated.Type() != object.INTEGER_OBJ {
+				t.Errorf("expected an integer or nothing, got %T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestFunctionArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		// default values
+		{"fn add(x, y = 10) { x + y }; add(1)", 11},
+		{"fn add(x, y = 10) { x + y }; add(1, 2)", 3},
+		{"fn scale(x, y = x * 2) { y }; scale(4)", 8},
+		{"let n = 5; fn f(x = n) { x }; f()", 5},
+		// rest parameters
+		{"fn count(...rest) { len(rest) }; count()", 0},
+		{"fn count(...rest) { len(rest) }; count(1, 2, 3)", 3},
+		{"fn second(first, ...rest) { rest[0] }; second(1, 2, 3)", 2},
+		{"fn f(x, y = 2, ...rest) { x + y + len(rest) }; f(1)", 3},
+		{"fn f(x, y = 2, ...rest) { x + y + len(rest) }; f(1, 5, 9, 9)", 8},
+		{"fn f(...rest) { len(rest) }; f([1, 2])", 1},
+		// named arguments
+		{"fn sub(x, y) { x - y }; sub(y: 1, x: 10)", 9},
+		{"fn sub(x, y) { x - y }; sub(10, y: 1)", 9},
+		{"fn f(x, y = 2, z = 3) { x * 100 + y * 10 + z }; f(1, z: 9)", 129},
+		// errors
+		{"fn add(x, y) { x + y }; add(1)", "wrong number of arguments passed to add. Got 1 wanted 2"},
+		{"fn add(x, y) { x + y }; add(1, 2, 3)", "wrong number of arguments passed to add. Got 3 wanted 2"},
+		{"fn add(x, y = 1) { x + y }; add()", "wrong number of arguments passed to add. Got 0 wanted 1 to 2"},
+		// the defaults are always at the end (the parser rejects fn(x = 1, y)), so the required ones come first
+		{"fn f(x, y = 2, z = 3) { x }; f()", "wrong number of arguments passed to f. Got 0 wanted 1 to 3"},
+		{"fn f(x, y = 2, z = 3) { x }; f(1, 2, 3, 4)", "wrong number of arguments passed to f. Got 4 wanted 1 to 3"},
+		{"fn f(x = 1, y = 2) { x + y }; f(1, 2, 3)", "wrong number of arguments passed to f. Got 3 wanted 0 to 2"},
+		{"fn f(x, ...rest) { x }; f()", "wrong number of arguments passed to f. Got 0 wanted at least 1"},
+		{"let add = fn(x, y) { x + y }; add(1)", "wrong number of arguments passed to anonymous function. Got 1 wanted 2"},
+		{"fn add(x, y) { x + y }; add(1, z: 2)", "add has no parameter named z"},
+		{"fn f(...rest) { rest }; f(rest: 2)", "f has no parameter named rest"},
+		{"fn add(x, y) { x + y }; add(1, x: 2)", "add got more than one value for parameter x"},
+		{"fn f(x = missing) { x }; f()", "identifier not found: missing"},
+		{"len(x: \"a\")", "builtin functions don't take named arguments"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestFunctionNames(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedName string
+	}{
+		{"fn double(x) { x * 2 }; double", "double"},
+		{"fn(x) { x * 2 }", ""},
+		{"let double = fn(x) { x * 2 }; double", ""},
+		{"fn outer() { fn inner() { 1 }; inner }; outer()", "inner"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		fn, ok := evaluated.(*object.Function)
+		if !ok {
+			t.Errorf("object is not a Function, got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if fn.Name != tt.expectedName {
+			t.Errorf("wrong function name, expected %q, got %q", tt.expectedName, fn.Name)
+		}
+
+		expectedPrefix := "fn("
+		if tt.expectedName != "" {
+			expectedPrefix = "fn " + tt.expectedName + "("
+		}
+
+		if !strings.HasPrefix(fn.Inspect(), expectedPrefix) {
+			t.Errorf("wrong Inspect(), expected it to start with %q, got %q", expectedPrefix, fn.Inspect())
+		}
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := `
 	let newAdder = fn(x) {
@@ -363,6 +770,35 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestStringInterpolation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let name = "Boar"; "Hello ${name}!"`, "Hello Boar!"},
+		{`let items = [1, 2, 3]; "you have ${len(items)} items"`, "you have 3 items"},
+		{`"${1 + 1} ${1.5 * 2} ${1 < 2} ${[1, "a"]}"`, "2 3.0 true [1, a]"},
+		{`let x = 2; "${x} squared is ${x * x}"`, "2 squared is 4"},
+		{`let h = {"k": "v"}; "${h["k"]}"`, "v"},
+		{`let who = "world"; "a ${"nested ${who}"} b"`, "a nested world b"},
+		{`"\${not interpolated}"`, "${not interpolated}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. expected=%q, got=%q", tt.expected, str.Value)
+		}
+	}
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -386,6 +822,12 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`rest([])`, nil},
 		{`push([], 1)`, []int{1}},
 		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
+		{`len("héllo")`, 5},
+		{`len("🐗🐗")`, 2},
+		{`bytes("hé")`, []int{104, 195, 169}},
+		{`len(bytes("🐗"))`, 4},
+		{`bytes(1)`, "argument to `bytes` must be STRING, got INTEGER"},
+		{`slice("héllo", 2, 1)`, "slice start index 2 is bigger than the end index 1"},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -430,6 +872,43 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestUnicodeStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"héllo"[1]`, "é"},
+		{`"名前"[0]`, "名"},
+		{`"🐗"[0]`, "🐗"},
+		{`"héllo"[5]`, nil},
+		{`"héllo"[-1]`, nil},
+		{`slice("héllo", 1, 3)`, "él"},
+		{`slice("héllo", 1)`, "éllo"},
+		{`slice("héllo", 0, 100)`, "héllo"},
+		{`slice("héllo", 6)`, ""},
+		{`let café = "☕"; café`, "☕"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		if tt.expected == nil {
+			testNullObject(t, evaluated)
+			continue
+		}
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value for %s. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -535,12 +1014,12 @@ func TestHashLiterals(t *testing.T) {
 		FALSE.HashKey():                            6,
 	}
 
-	if len(result.Pairs) != len(expected) {
-		t.Fatalf("Hash has wrong number of pairs, got %d", len(result.Pairs))
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong number of pairs, got %d", result.Len())
 	}
 
 	for expectedKey, expectedValue := range expected {
-		pair, ok := result.Pairs[expectedKey]
+		pair, ok := result.Get(expectedKey)
 
 		if !ok {
 			t.Errorf("no pair for given key in Pairs")
@@ -749,45 +1228,117 @@ func TestHashDigging(t *testing.T) {
 	}
 }
 
-func TestArrayIndexAssignments(t *testing.T) {
+func TestHashOrder(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected interface{}
+		expected string
 	}{
-		{`let arr = ["a", "b", "c"]; arr[0] = 5; arr[0]`, 5},
-		{`let arr = ["tom", "jerry"]; arr[1] = "bombadil"; arr[1]`, "bombadil"},
-		{`let arr = ["Smitty Werbenjagermanjensen","He was number", "two"]; arr[2] = "one"; arr[2]`, "one"},
+		{`{"b": 1, "a": 2, "c": 3}`, `{"b" : "1", "a" : "2", "c" : "3"}`},
+		{`let h = {"b": 1, "a": 2}; h["c"] = 3; h["b"] = 4; h`, `{"b" : "4", "a" : "2", "c" : "3"}`},
+		{`let h = {"b": 1, "a": 2, "c": 3}; h.delete("a"); h`, `{"b" : "1", "c" : "3"}`},
+		{`let h = {"b": 1, "a": 2}; h.delete("b"); h["b"] = 5; h`, `{"a" : "2", "b" : "5"}`},
+		{`{"z": 1, "y": 2, "x": 3}.toArray()`, `[z, 1, y, 2, x, 3]`},
+		{`let out = []; for (k, v in {"z": 1, "y": 2, "x": 3}) { out = push(out, k) }; out`, `[z, y, x]`},
+		{`keys({"b": 1, "a": 2, 3: "c"})`, `[b, a, 3]`},
+		{`{"b": 1, "a": 2}.values()`, `[1, 2]`},
+		{`{"b": 1, "a": [2]}.entries()`, `[[b, 1], [a, [2]]]`},
+		{`keys({})`, `[]`},
+		{`{"a": 1, "b": 2}.merge({"c": 3, "a": 10})`, `{"a" : "10", "b" : "2", "c" : "3"}`},
+		{`merge({"a": 1}, {"b": 2}, {"a": 3})`, `{"a" : "3", "b" : "2"}`},
+		// merge makes a new hash, frozen ones are fine
+		{`let h = freeze({"a": 1}); let m = h.merge({"b": 2}); m["a"] = 5; [h, m]`, `[{"a" : "1"}, {"a" : "5", "b" : "2"}]`},
+		// the keys of a caught error keep the thrown hash's order
+		{`try { throw {"kind": "ConfigError", "code": 7} } catch (e) { e.keys() }`, `[kind, code, message, line, column, file, function, value, stack]`},
 	}
 
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
 
-		_, isInt := evaluated.(*object.Integer)
-		expectedInt, expectedIsInt := tt.expected.(int)
-
-		actualString, isString := evaluated.(*object.String)
-		expectedString, expectedIsString := tt.expected.(string)
-
-		if isInt && expectedIsInt {
-			testIntegerObject(t, evaluated, int64(expectedInt))
-		}
-
-		if isString && expectedIsString {
-			if actualString.Value != expectedString {
-				t.Errorf("Expected %s, got %s", tt.expected, evaluated)
-			}
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q, expected %s, got %s", tt.input, tt.expected, evaluated.Inspect())
 		}
 	}
 }
 
-func TestArrayMapFunction(t *testing.T) {
-	expectedResults := [][]interface{}{
-		{3, 4, 5},
-		{4, 8, 12},
-	}
+func TestHashHasAndSize(t *testing.T) {
 	tests := []struct {
 		input    string
-		expected []interface{}
+		expected interface{}
+	}{
+		{`{"a": 1}.has("a")`, true},
+		{`has({"a": 1}, "b")`, false},
+		{`let h = {"a": 1}; h.delete("a"); h.has("a")`, false},
+		// a key with a null value is still there
+		{`let h = {"a": 1}; h["a"] = puts(); h.has("a")`, true},
+		{`{1: "a", true: "b"}.has(true)`, true},
+		{`size({})`, 0},
+		{`let h = {"a": 1, "b": 2}; h["c"] = 3; h.delete("a", "x"); h.size()`, 2},
+		{`has({"a": 1}, [1])`, "Unusable value as hash key: ARRAY"},
+		{`size([1, 2])`, "argument to `size` must be HASH, got ARRAY"},
+		{`keys()`, "wrong number of arguments, got 0 wanted 1"},
+		{`merge({"a": 1}, [1])`, "argument to `merge` must be HASH, got ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q, got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message for %q, expected %q, got %q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestArrayIndexAssignments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let arr = ["a", "b", "c"]; arr[0] = 5; arr[0]`, 5},
+		{`let arr = ["tom", "jerry"]; arr[1] = "bombadil"; arr[1]`, "bombadil"},
+		{`let arr = ["Smitty Werbenjagermanjensen","He was number", "two"]; arr[2] = "one"; arr[2]`, "one"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		_, isInt := evaluated.(*object.Integer)
+		expectedInt, expectedIsInt := tt.expected.(int)
+
+		actualString, isString := evaluated.(*object.String)
+		expectedString, expectedIsString := tt.expected.(string)
+
+		if isInt && expectedIsInt {
+			testIntegerObject(t, evaluated, int64(expectedInt))
+		}
+
+		if isString && expectedIsString {
+			if actualString.Value != expectedString {
+				t.Errorf("Expected %s, got %s", tt.expected, evaluated)
+			}
+		}
+	}
+}
+
+func TestArrayMapFunction(t *testing.T) {
+	expectedResults := [][]interface{}{
+		{3, 4, 5},
+		{4, 8, 12},
+	}
+	tests := []struct {
+		input    string
+		expected []interface{}
 	}{
 		{`let arr = [1,2,3]; let addTwo = fn(x) { x + 2; }; let arr = map(arr, addTwo); arr`, expectedResults[0]},
 		{`let arr = [1,2,3]; let addTwo = fn(x) { x + 2; }; let arr = arr.map(addTwo); arr`, expectedResults[0]},
@@ -1006,6 +1557,77 @@ func TestArrayInternalFunctionCall(t *testing.T) {
 		}
 	}
 }
+func TestMethodCallChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3].first()", 1},
+		{"let double = fn(x) { x * 2 }; [1, 2, 3].map(double).last()", 6},
+		{"let double = fn(x) { x * 2 }; [1, 2, 3].map(double).slice(1).first()", 4},
+		{`let person = {"tags": [5, 6]}; person["tags"].last()`, 6},
+		{`"héllo".len()`, 5},
+		{"fn get() { [7, 8] }; get().first()", 7},
+		{"[1, 2].pop(missing)", "identifier not found: missing"},
+		{"[1, 2].nope()", "identifier not found: nope"},
+		{"missing.first()", "identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestPropertyAccess(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let person = {"age": 30}; person.age`, 30},
+		{`let person = {"address": {"zip": 12345}}; person.address.zip`, 12345},
+		{`let person = {"tags": [1, 2, 3]}; person.tags.last()`, 3},
+		{`let person = {"tags": [1, 2, 3]}; person.tags[1]`, 2},
+		{`let person = {"age": 30}; person.name`, nil},
+		{`{"age": 30}.age`, 30},
+		{"[1, 2].length", "property access not supported: ARRAY.length"},
+		{"let x = 5; x.value", "property access not supported: INTEGER.value"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestAssignmentExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -1014,6 +1636,9 @@ func TestAssignmentExpressions(t *testing.T) {
 		{"let a = 5; a = 4; a;", 4},
 		{"let a = 5 * 5; a = 4 * 4; a;", 16},
 		{"let a = 5; let b = a; b = 300; b;", 300},
+		// the variable is updated where it was declared
+		{"let c = 0; let inc = fn() { c = c + 1 }; inc(); inc(); c", 2},
+		{"fn counter() { let n = 0; fn() { n = n + 1 } }; let next = counter(); next(); next()", 2},
 	}
 
 	for _, tt := range tests {
@@ -1021,6 +1646,295 @@ func TestAssignmentExpressions(t *testing.T) {
 	}
 }
 
+func TestClosureMutation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		// counters: every call to the factory gets its own variable
+		{"fn counter() { let n = 0; fn() { n = n + 1 } }; let a = counter(); let b = counter(); a(); a(); b(); a() * 10 + b()", 32},
+		// accumulators
+		{"fn accumulator(total) { fn(x) { total = total + x; total } }; let acc = accumulator(10); acc(5); acc(-2)", 13},
+		// closures made by the same call share its variables
+		{"fn pair() { let n = 0; [fn() { n = n + 1 }, fn() { n }] }; let p = pair(); p[0](); p[0](); p[1]()", 2},
+		// two levels up
+		{"fn outer() { let n = 1; fn middle() { fn inner() { n = n * 5 }; inner() }; middle(); middle(); n }; outer()", 25},
+		// a global from a function
+		{"let calls = 0; fn track() { calls = calls + 1 }; for (x in [1, 2, 3]) { track() }; calls", 3},
+		// a callback passed to a builtin
+		{"let sum = 0; [1, 2, 3].map(fn(x) { sum = sum + x }); sum", 6},
+		// recursion updating the same variable
+		{"fn f() { let depth = 0; fn down(n) { if (n > 0) { depth = depth + 1; down(n - 1) } }; down(4); depth }; f()", 4},
+		// a let in the closure shadows the outer variable instead of changing it
+		{"let x = 1; fn f() { let x = 2; x = 3 }; f(); x", 1},
+		{"fn f() { let x = 1; let g = fn() { let x = 10; x = 20 }; g(); x }; f()", 1},
+		// a parameter is a variable of the call
+		{"fn f(x) { let set = fn(v) { x = v }; set(7); x }; f(1)", 7},
+		// every iteration of a for-in loop has its own variable
+		{"let fns = []; for (x in [1, 2, 3]) { fns = push(fns, fn() { x = x * 10; x }) }; fns[0]() + fns[1]() + fns[2]() + fns[2]()", 360},
+		// the catch parameter
+		{"let get = 0; try { throw 5 } catch (e) { let code = e.value; get = fn() { code = code + 1 } }; get(); get()", 7},
+		// memo caches: the hash is shared, the counter is updated where it was declared
+		{
+			"fn memoize(f) { let cache = {}; fn(n) { if (!cache[n]) { cache[n] = f(n) }; cache[n] } };" +
+				"let computed = 0; let double = memoize(fn(n) { computed = computed + 1; n * 2 });" +
+				"double(2); double(3); double(2); double(2); computed",
+			2,
+		},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if !testIntegerObject(t, evaluated, tt.expected) {
+			t.Errorf("wrong result for %q", tt.input)
+		}
+	}
+}
+
+func TestUseBeforeDeclaration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// closures see the variables of their block, even the ones declared after them
+		{`let x = "g"; fn f() { let h = fn() { x }; let r = h(); let x = "l"; r }; f()`, "ERROR: 1:38: x used before its declaration (in fn h)"},
+		{"fn f() { let set = fn() { n = 1 }; set(); let n = 0; n }; f()", "ERROR: 1:29: n used before its declaration (in fn set)"},
+		{"fn f() { g(); let x = 1; fn g() { x } }; f()", "ERROR: 1:35: x used before its declaration (in fn g)"},
+		// once the let ran they work
+		{`let x = "g"; fn f() { let h = fn() { x }; let x = "l"; h() }; f()`, "l"},
+		// code before the let uses the outer variable
+		{`let x = "g"; fn f() { let r = x; let x = "l"; r + x }; f()`, "gl"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		if errObj, ok := evaluated.(*object.Error); ok {
+			if errObj.Inspect() != tt.expected || errObj.Kind != object.NAME_ERROR {
+				t.Errorf("wrong error for %q, expected %q, got %s %q", tt.input, tt.expected, errObj.KindName(), errObj.Inspect())
+			}
+			continue
+		}
+
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q, expected %s, got %s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestBlockScopes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		// a let in a block shadows the outer variable until the block ends
+		{"let x = 1; if (true) { let x = 2; x = 3 }; x", 1},
+		{"let x = 1; if (true) { x = 2 }; x", 2},
+		{"let x = 1; let y = 0; { let x = 10; y = x }; x + y", 11},
+		{"fn f() { let total = 0; let i = 0; while (i < 3) { let step = i * 10; total = total + step; i = i + 1 }; total }; f()", 30},
+		{"let n = 0; match (1) { 1 => { let n = 5; n } }", 5},
+		{"let n = 0; match (1) { 1 => { let n = 5 } }; n", 0},
+		// the counter of a for loop only exists inside of it, every iteration has its own
+		{"let i = 100; for (let i = 0; i < 3; i = i + 1) { }; i", 100},
+		{"let fns = []; for (let i = 0; i < 3; i = i + 1) { fns = push(fns, fn() { i }) }; fns[0]() * 100 + fns[1]() * 10 + fns[2]()", 12},
+		{"let fns = []; let i = 0; while (i < 3) { let j = i; fns = push(fns, fn() { j }); i = i + 1 }; fns[0]() * 100 + fns[1]() * 10 + fns[2]()", 12},
+		// changing the counter in the body carries over to the next iteration
+		{"let count = 0; for (let i = 0; i < 10; i = i + 1) { i = i + 2; count = count + 1 }; count", 4},
+		// fn declarations in a block are hoisted to the top of the block
+		{"{ let x = f(); fn f() { 4 }; x }", 4},
+		// a constant declared in a loop body is a new variable every iteration
+		{"let sum = 0; for (x in [1, 2, 3]) { const double = x * 2; sum = sum + double }; sum", 12},
+		{"let sum = 0; let i = 0; while (i < 3) { const step = i; sum = sum + step; i = i + 1 }; sum", 3},
+		{"if (true) { let hidden = 1 }; hidden", "identifier not found: hidden"},
+		{"for (let i = 0; i < 3; i = i + 1) { let seen = i }; seen", "identifier not found: seen"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			if !testIntegerObject(t, evaluated, int64(expected)) {
+				t.Errorf("wrong result for %q", tt.input)
+			}
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q, got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message for %q, expected %q, got %q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"const x = 5; x * 2", 10},
+		{"fn f() { const y = 2; y + 1 }; f()", 3},
+		// every call gets its own constant
+		{"fn f(n) { const y = n; y }; f(1) + f(2)", 3},
+		// shadowing a constant in an inner scope is fine
+		{"const x = 1; fn f() { let x = 2; x = 3; x }; f() + x", 4},
+		{"const x = 1; x = 2", "cannot assign to constant x"},
+		{"const x = 1; let x = 2", "cannot assign to constant x"},
+		{"const x = 1; const x = 2", "cannot assign to constant x"},
+		{"fn f() { const y = 1; y = 2 }; f()", "cannot assign to constant y"},
+		{"fn f() { const y = 1; fn() { y = 2 }() }; f()", "cannot assign to constant y"},
+		// a regular runtime error that can be caught, the constant doesn't change
+		{"const n = 0; fn inc() { n = n + 1 }; let caught = 0; try { inc() } catch (e) { caught = 1 }; caught + n", 1},
+		// the value can still change, the variable can't: see freeze()
+		{"const arr = [1]; arr[0] = 2; arr[0]", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q, got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message for %q, expected %q, got %q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`const config = freeze({"retries": 3}); config["retries"]`, 3},
+		{`let arr = freeze([1, 2]); len(arr) + first(arr)`, 3},
+		// copies aren't frozen
+		{`let arr = freeze([1, 2]); let more = push(arr, 3); more[0] = 10; more[0] + len(arr)`, 12},
+		{`freeze(5)`, 5},
+		{`let arr = freeze([1, 2]); arr[0] = 5`, "cannot modify a frozen array"},
+		{`let arr = freeze([1, 2]); arr.pop()`, "cannot modify a frozen array"},
+		{`let arr = freeze([1, 2]); shift(arr)`, "cannot modify a frozen array"},
+		{`let h = freeze({"a": 1}); h["b"] = 2`, "cannot modify a frozen hash"},
+		{`let h = freeze({"a": 1}); delete(h, "a")`, "cannot modify a frozen hash"},
+		// deeply frozen
+		{`let h = freeze({"servers": [{"host": "a"}]}); h["servers"][0]["host"] = "b"`, "cannot modify a frozen hash"},
+		{`let h = freeze({"servers": [1]}); h.servers.pop()`, "cannot modify a frozen array"},
+		{`let arr = [1]; arr[0] = arr; freeze(arr); arr[0][1] = 2`, "cannot modify a frozen array"},
+		{`freeze()`, "wrong number of arguments passed to freeze. Got 0 wanted 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q, got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message for %q, expected %q, got %q", tt.input, expected, errObj.Message)
+			}
+		}
+	}
+}
+
+// Nodes evaluated one by one (no resolver) look their variables up by name, assignments still go to the defining scope
+func TestAssignWithoutResolver(t *testing.T) {
+	program := parser.New(lexer.New("let c = 0; let inc = fn() { c = c + 1 }; inc(); inc(); c")).ParseProgram()
+
+	env := object.NewEnvironment()
+	var result object.Object
+	for _, statement := range program.Statements {
+		result = Eval(statement, env)
+	}
+
+	testIntegerObject(t, result, 2)
+
+	assignment := parser.New(lexer.New("missing = 1")).ParseProgram().Statements[0]
+	errObj, ok := Eval(assignment, env).(*object.Error)
+	if !ok || errObj.Message != `Identifier "missing" not found` {
+		t.Errorf("expected a NameError, got %+v", errObj)
+	}
+}
+
+const fibonacciProgram = `
+	fn fib(n) {
+		let a = 0;
+		let b = 1;
+		for (let i = 0; i < n; i = i + 1) {
+			let next = a + b;
+			a = b;
+			b = next;
+		}
+		a
+	}
+	fn fibRec(n) { if (n < 2) { n } else { fibRec(n - 1) + fibRec(n - 2) } }
+	fib(30) + fibRec(15)
+	`
+
+// fib(30) + fibRec(15)
+const fibonacciResult = 832040 + 610
+
+/**
+go test ./evaluator -run XXX -bench Fibonacci
+
+BenchmarkFibonacci goes through the resolver, variables are read and written by slot.
+BenchmarkFibonacciByName skips it like TestAssignWithoutResolver does, so every variable is looked up by name
+(Environment.Get / Set walking the scopes), which is how every program ran before the resolver.
+**/
+func BenchmarkFibonacci(b *testing.B) {
+	program := parser.New(lexer.New(fibonacciProgram)).ParseProgram()
+
+	for i := 0; i < b.N; i++ {
+		env := object.NewEnvironment()
+		loadBuiltInMethods(env)
+		result := Eval(program, env)
+
+		if i == 0 {
+			checkFibonacciResult(b, result)
+		}
+	}
+}
+
+func BenchmarkFibonacciByName(b *testing.B) {
+	program := parser.New(lexer.New(fibonacciProgram)).ParseProgram()
+
+	for i := 0; i < b.N; i++ {
+		env := object.NewEnvironment()
+		loadBuiltInMethods(env)
+		hoistFunctions(program.Statements, env)
+
+		var result object.Object
+		for _, statement := range program.Statements {
+			result = Eval(statement, env)
+		}
+
+		if i == 0 {
+			checkFibonacciResult(b, result)
+		}
+	}
+}
+
+func checkFibonacciResult(b *testing.B, result object.Object) {
+	if integer, ok := result.(*object.Integer); !ok || integer.Value != fibonacciResult {
+		b.Fatalf("wrong result, expected %d, got %s", fibonacciResult, result.Inspect())
+	}
+}
+
 func TestForLoopStatement(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -1050,3 +1964,545 @@ func TestForLoopStatement(t *testing.T) {
 
 	}
 }
+
+func TestWhileLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 0; while (x < 10) { x = x + 1 }; x", 10},
+		{"let x = 0; while (false) { x = x + 1 }; x", 0},
+		{"let x = 0; while (true) { x = x + 1; if (x == 5) { break } }; x", 5},
+		{"let x = 0; let evens = 0; while (x < 10) { x = x + 1; if (x % 2 == 1) { continue } evens = evens + 1 }; evens", 5},
+		{"let sum = 0; for (let i = 0; i < 10; i = i + 1) { if (i == 3) { continue } if (i == 6) { break } sum = sum + i }; sum", 12},
+		// break only stops the innermost loop
+		{"let count = 0; let i = 0; while (i < 3) { i = i + 1; while (true) { count = count + 1; break } }; count", 3},
+		// return stops the loop and the function
+		{"let find = fn(arr, target) { let i = 0; while (i < len(arr)) { if (arr[i] == target) { return i } i = i + 1 } -1 }; find([5, 6, 7], 7)", 2},
+		{"let f = fn() { for (let i = 0; i < 10; i = i + 1) { if (i == 4) { return i * 10 } } }; f()", 40},
+		{"let x = 0; while (x < 3) { x = x + 1; missing }", "identifier not found: missing"},
+		{"while (missing) { 1 }", "identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestForInLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		// the loop evaluates to the last value of its body
+		{"for (x in [1, 2, 3]) { x * 2 }", 6},
+		{"let total = [0]; for (x in [1, 2, 3]) { total[0] = total[0] + x }; total[0]", 6},
+		{"let total = [0]; for (i, x in [10, 20]) { total[0] = total[0] + i * x }; total[0]", 20},
+		{"let total = [0]; for (v in {\"a\": 1, \"b\": 2}) { total[0] = total[0] + len(v) }; total[0]", 2},
+		{"let total = [0]; for (k, v in {\"a\": 1, \"b\": 2}) { total[0] = total[0] + v }; total[0]", 3},
+		{"let count = [0]; for (ch in \"héllo\") { count[0] = count[0] + 1 }; count[0]", 5},
+		{"for (i, ch in \"abc\") { i }", 2},
+		{"let total = [0]; for (i in range(5)) { total[0] = total[0] + i }; total[0]", 10},
+		{"let total = [0]; for (i in range(2, 10, 3)) { total[0] = total[0] + i }; total[0]", 15},
+		{"for (i in range(3, 0, -1)) { i }", 1},
+		{"let count = [0]; for (x in range(9223372036854775800, 9223372036854775807, 5)) { count[0] = count[0] + 1 }; count[0]", 2},
+		{"let f = fn(arr, min) { for (x in arr) { if (x > min) { return x } } }; f([1, 2, 3, 4], 2)", 3},
+		{"let last = [0]; for (x in range(100)) { if (x % 2 == 0) { continue } if (x > 6) { break } last[0] = x }; last[0]", 5},
+		// the loop variables only exist inside of the loop
+		{"let x = 10; for (x in [1, 2, 3]) { x }; x", 10},
+		{"for (x in 5) { x }", "cannot iterate over INTEGER"},
+		{"for (x in [1]) { missing }", "identifier not found: missing"},
+		{"for (x in range(1, 2, 0)) { x }", "range step can't be 0"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestTryCatchFinally(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		// the statement evaluates to the value of the try or the catch block
+		{"try { 1 } catch { 2 }", 1},
+		{"try { throw 1 } catch { 2 }", 2},
+		{"try { throw 1; 5 } catch (e) { e.value + 10 }", 11},
+		// runtime errors, builtins included, can be caught
+		{"try { 1 / 0 } catch (e) { 3 }", 3},
+		{"try { len(1, 2) } catch (e) { 4 }", 4},
+		{"try { 1 / 0 } catch { 5 }", 5},
+		{"fn risky() { throw 1 }; try { risky(); 0 } catch { 6 }", 6},
+		{"fn safe(x) { try { 10 / x } catch { -1 } }; safe(2) + safe(0)", 4},
+		// finally always runs
+		{"let log = [0]; try { 1 } finally { log[0] = 7 }; log[0]", 7},
+		{"let log = [0]; try { throw 1 } catch { 2 } finally { log[0] = 8 }; log[0]", 8},
+		{"let log = [0]; let f = fn() { try { return 1 } finally { log[0] = 9 } }; f() + log[0]", 10},
+		{"let f = fn() { try { 1 } finally { return 11 } }; f()", 11},
+		// return / break / continue aren't errors
+		{"let f = fn() { try { return 12 } catch { 0 } }; f()", 12},
+		{"let count = [0]; for (x in [1, 2, 3]) { try { if (x == 2) { break } count[0] = count[0] + x } catch { 0 } }; count[0]", 1},
+		{"let count = [0]; for (x in [1, 2, 3]) { try { if (x == 2) { continue } count[0] = count[0] + x } finally { 0 } }; count[0]", 4},
+		// one bad record doesn't stop the rest
+		{"let ok = [0]; for (x in [1, 0, 2, \"a\", 4]) { try { 8 / x; ok[0] = ok[0] + 1 } catch { 0 } }; ok[0]", 3},
+		// errors that aren't caught
+		{"try { throw \"first\" } finally { 0 }", "first"},
+		{"try { 1 } catch { 2 } finally { throw \"from finally\" }", "from finally"},
+		{"try { throw \"a\" } catch (e) { throw \"b\" }", "b"},
+		{"try { throw \"a\" } catch (e) { missing }", "identifier not found: missing"},
+		{"throw 5", "5"},
+		{"throw missing", "identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestCaughtErrorFields(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { 1 / 0 } catch (e) { e.kind }", "ZeroDivisionError"},
+		{"try { 1 / 0 } catch (e) { e.message }", "division by zero: 1 / 0"},
+		{"try { 1 + true } catch (e) { e.kind }", "TypeError"},
+		// a local that was never given a value
+		{"fn f() { g(); let x = 1; fn g() { x } }; try { f() } catch (e) { e.kind }", "NameError"},
+		{"fn f(x) { x }; try { f() } catch (e) { e.kind }", "ArgumentError"},
+		{"try { len(1, 2) } catch (e) { e.kind }", "ArgumentError"},
+		{"try { len(1) } catch (e) { e.kind }", "TypeError"},
+		{"try { has({\"a\": 1}, [1]) } catch (e) { e.kind }", "TypeError"},
+		{"try { let h = {}; h[[1]] = 2 } catch (e) { e.kind }", "TypeError"},
+		{"try { slice([1, 2], -1) } catch (e) { e.kind }", "ArgumentError"},
+		{"try { range(1, 2, 0) } catch (e) { e.kind }", "ArgumentError"},
+		{"try { bytes(1) } catch (e) { e.kind }", "TypeError"},
+		{"try { size([1]) } catch (e) { e.kind }", "TypeError"},
+		{"try { match (3) { 1 => 10 } } catch (e) { e.kind }", "MatchError"},
+		{"try { throw \"oops\" } catch (e) { e.kind }", "Error"},
+		{"try { throw \"oops\" } catch (e) { e.message }", "oops"},
+		{"try {\n  throw \"oops\"\n} catch (e) { \"${e.line}:${e.column}\" }", "2:3"},
+		{"fn risky() { throw \"oops\" }; try { risky() } catch (e) { e.function }", "risky"},
+		{"try { throw {\"kind\": \"ValidationError\", \"message\": \"bad\", \"id\": \"r1\"} } catch (e) { e.kind }", "ValidationError"},
+		{"try { throw {\"kind\": \"ValidationError\", \"message\": \"bad\", \"id\": \"r1\"} } catch (e) { e.message }", "bad"},
+		{"try { throw {\"kind\": \"ValidationError\", \"message\": \"bad\", \"id\": \"r1\"} } catch (e) { e.id }", "r1"},
+		// throwing a caught error again keeps its kind and location
+		{"try {\n  try { 1 / 0 } catch (e) { throw e }\n} catch (e) { \"${e.kind} ${e.line}:${e.column}\" }", "ZeroDivisionError 2:11"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("wrong value for %q. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+}
+
+func TestStackTraces(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedFrames []string
+	}{
+		{"1 + true", []string{}},
+		{"len(1, 2)", []string{}},
+		{"fn f(x) { x / 0 }\nf(1)", []string{"at f(1) (2:2)"}},
+		{
+			"fn inner(x, label) { x / 0 }\nlet middle = fn(x) { inner(x, \"label\") };\nfn outer() { middle(2) }\nouter()",
+			[]string{`at inner(2, "label") (2:27)`, "at middle(2) (3:20)", "at outer() (4:6)"},
+		},
+		{"fn rec(n) { if (n == 0) { throw \"done\" } rec(n - 1) }\nrec(2)", []string{"at rec(0) (1:45)", "at rec(1) (1:45)", "at rec(2) (2:4)"}},
+		{"fn f(x, y) { x }\nf(1)", []string{"at f(1) (2:2)"}},
+		{"fn f(a, b) { -true }\nf(a: [1, 2], b: fn(x) { x })", []string{"at f(a: [1, 2], b: fn) (2:2)"}},
+		{"fn f(s) { -true }\nf(\"a string that is too long to show\")", []string{`at f("a string that is...) (2:2)`}},
+		{"fn() { -true }()", []string{"at anonymous function() (1:15)"}},
+		{"[1, 2].first(3)", []string{}},
+		{"fn bad(arr, x) { -true }\n[1, 2].bad(3)", []string{"at bad([1, 2], 3) (2:7)"}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q, got %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		frames := []string{}
+		for _, frame := range errObj.Trace {
+			frames = append(frames, frame.String())
+		}
+
+		if strings.Join(frames, "\n") != strings.Join(tt.expectedFrames, "\n") {
+			t.Errorf("wrong stack trace for %q\nexpected:\n%s\ngot:\n%s", tt.input, strings.Join(tt.expectedFrames, "\n"), strings.Join(frames, "\n"))
+		}
+	}
+}
+
+func TestCaughtStackTrace(t *testing.T) {
+	input := `
+	fn inner() { throw "oops" }
+	fn outer() { inner() }
+	try { outer() } catch (e) { e.stack }
+	`
+
+	evaluated := testEval(input)
+
+	arr, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := []string{"at inner() (3:20)", "at outer() (4:13)"}
+
+	if len(arr.Elements) != len(expected) {
+		t.Fatalf("wrong number of frames, expected %d, got %d", len(expected), len(arr.Elements))
+	}
+
+	for i, frame := range expected {
+		testStringObject(t, arr.Elements[i], frame)
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedInspect string
+	}{
+		{"5 + true;", "ERROR: 1:3: type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 1;\nlet y = x;\nfoobar", "ERROR: 3:1: identifier not found: foobar"},
+		{"let f = fn(x) {\n  x + missing\n};\nf(1)", "ERROR: 2:7: identifier not found: missing"},
+		{"if (true) {\n    -true\n}", "ERROR: 2:5: unknown operator: -BOOLEAN"},
+		{"fn f(x) {\n  x / 0\n}\nf(1)", "ERROR: 2:5: division by zero: 1 / 0 (in fn f)"},
+		// the innermost named function is reported
+		{"fn outer() { inner() }\nfn inner() { -true }\nouter()", "ERROR: 2:14: unknown operator: -BOOLEAN (in fn inner)"},
+		{"fn outer() { fn() { -true }() }\nouter()", "ERROR: 1:21: unknown operator: -BOOLEAN (in fn outer)"},
+		// an anonymous function is named after the variable it's called through
+		{"fn outer() { let h = fn() { -true }; h() }\nouter()", "ERROR: 1:29: unknown operator: -BOOLEAN (in fn h)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Inspect() != tt.expectedInspect {
+			t.Errorf("wrong error, got %q expected %q", errObj.Inspect(), tt.expectedInspect)
+		}
+	}
+}
+
+// Inputs that used to crash the Go runtime, each one should give back an error of the right kind instead
+func TestNoPanics(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedKind    string
+		expectedMessage string
+	}{
+		{"1 / 0", object.ZERO_DIVISION_ERROR, "division by zero: 1 / 0"},
+		{"1 % 0", object.ZERO_DIVISION_ERROR, "division by zero: 1 % 0"},
+		{"let arr = [1, 2]; arr[5] = 3", object.INDEX_ERROR, "index 5 out of range for an array of length 2"},
+		{"let arr = [1, 2]; arr[-1] = 3", object.INDEX_ERROR, "index -1 out of range for an array of length 2"},
+		{"[].pop()", object.INDEX_ERROR, "pop from an empty array"},
+		{"shift([])", object.INDEX_ERROR, "shift from an empty array"},
+		{"slice([1, 2, 3], 2, 1)", object.INDEX_ERROR, "slice start index 2 is bigger than the end index 1"},
+		{"slice(\"abc\", 2, 1)", object.INDEX_ERROR, "slice start index 2 is bigger than the end index 1"},
+		{"first()", object.ARGUMENT_ERROR, "wrong number of arguments passed to first. Got 0 wanted 1"},
+		{"push([1])", object.ARGUMENT_ERROR, "wrong number of arguments passed to push. Got 1 wanted 2"},
+		{"map([1])", object.ARGUMENT_ERROR, "wrong number of arguments passed to map. Got 1 wanted 2"},
+		{"slice()", object.ARGUMENT_ERROR, "wrong number of arguments passed to slice. Got 0 wanted 1 to 3"},
+		{"map([1], 2)", object.TYPE_ERROR, "second argument to `map` should be a function, got INTEGER instead"},
+		{"[1, 2].map(fn(x) { x / 0 })", object.ZERO_DIVISION_ERROR, "division by zero: 1 / 0"},
+		{"fn f() { let x = 1 }; f() + 1", object.TYPE_ERROR, "type mismatch: NULL + INTEGER"},
+		{"for (let i = 0; i + 1; i = i + 1) { i }", object.RUNTIME_ERROR, "Invalid loop condition type: INTEGER"},
+		{"fn f(n) { f(n + 1) }; f(0)", object.RECURSION_ERROR, "maximum call depth of 10000 exceeded"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if err.KindName() != tt.expectedKind {
+			t.Errorf("wrong error kind for %q. expected=%q, got=%q", tt.input, tt.expectedKind, err.KindName())
+		}
+
+		if err.Message != tt.expectedMessage {
+			t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, tt.expectedMessage, err.Message)
+		}
+	}
+}
+
+func TestExpressionsWithoutValue(t *testing.T) {
+	tests := []string{
+		"fn() { let x = 1 }()",
+		"fn() {}()",
+		"if (true) { let x = 1 }",
+		"dig({\"a\": 1}, \"b\")",
+		"first(valuesAt({\"a\": 1}, \"b\"))",
+	}
+
+	for _, input := range tests {
+		testNullObject(t, testEval(input))
+	}
+}
+
+func TestAssignmentValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let x = 1; x = 5", 5},
+		{"let x = 1; let y = 2; x = y = 3; x + y", 6},
+		// a single array argument is just an argument, not an implicit map
+		{"fn count(arr) { len(arr) }; count([1, 2, 3])", 3},
+		// the call depth goes back down when a RecursionError gets caught
+		{"fn f(n) { f(n + 1) }; try { f(0) } catch (e) { 0 }; fn g(n) { if (n == 0) { 7 } else { g(n - 1) } }; g(100)", 7},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+/**
+go test ./evaluator -fuzz FuzzEval
+
+Whatever the script does, evaluating it must not panic.
+A Go panic inside of Eval comes back as an InternalError (see evalProgram), so that's what we look for.
+Scripts that loop or recurse forever are fine, they're stopped by a step limit (see object.Context.MaxSteps).
+**/
+func FuzzEval(f *testing.F) {
+	seeds := []string{
+		"let arr = [1, 2]; arr[5] = 3",
+		"[].pop(); shift([]); slice([1, 2, 3], 2, 1)",
+		"first(); push([1]); map([1]); map([1], 2)",
+		"[1, 2].map(fn(x) { x / 0 })",
+		"fn f() { let x = 1 }; f() + 1",
+		"fn f(n) { f(n + 1) }; f(0)",
+		"fn add(x, y = x * 2, ...rest) { x + y + len(rest) }; add(1, 2, 3, 4) + add(y: 1, x: 2)",
+		"let h = {\"a\": {\"b\": 1}}; dig(h, \"a\", \"b\") + len(valuesAt(h, \"a\", \"c\"))",
+		"match (5 % 3) { 1 => \"one\", _ if true => \"${5 ** 2}\" }",
+		"let total = [0]; for (x in range(10, 0, -2)) { if (x == 4) { continue; } total[0] = total[0] + x }; total[0]",
+		"try { throw {\"kind\": \"Custom\"} } catch (e) { e.stack } finally { slice(\"héllo\", 1, 3) }",
+		"let i = 0; while (i < 10) { i = i + 1; if (i > 5) { break } }; -i / 2.5",
+		"const c = freeze([1, [2]]); c[1][0] = 3; c = 1",
+		"let a = [1]; a[0] = a; puts(a)",
+		"let h = {}; h[\"self\"] = h; \"${h}\"",
+	}
+
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := lexer.New(input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) > 0 {
+			return
+		}
+
+		env := object.NewEnvironment()
+		loadBuiltInMethods(env)
+
+		ctx := object.NewContext(strings.NewReader(""), io.Discard, io.Discard)
+		ctx.MaxSteps = 10000
+		env.SetContext(ctx)
+
+		result := Eval(program, env)
+		if err, ok := result.(*object.Error); ok && err.Kind == object.INTERNAL_ERROR {
+			t.Fatalf("evaluating %q panicked: %s", input, err.Message)
+		}
+	})
+}
+
+func TestStepLimit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 50) { i = i + 1 }; i", 50},
+		{"while (true) { }", "step limit of 100 exceeded"},
+		{"for (let i = 0; i < 1000; i = i + 1) { }", "step limit of 100 exceeded"},
+		{"for (x in range(1000)) { }", "step limit of 100 exceeded"},
+		{"fn f(n) { f(n + 1) }; f(0)", "step limit of 100 exceeded"},
+		// catching the error doesn't help, every step after the limit fails too
+		{"fn f() { try { f() } catch (e) { }; try { f() } catch (e) { } }; while (true) { f() }", "step limit of 100 exceeded"},
+	}
+
+	for _, tt := range tests {
+		program := parser.New(lexer.New(tt.input)).ParseProgram()
+		env := object.NewEnvironment()
+		loadBuiltInMethods(env)
+
+		ctx := object.NewContext(strings.NewReader(""), io.Discard, io.Discard)
+		ctx.MaxSteps = 100
+		env.SetContext(ctx)
+
+		evaluated := Eval(program, env)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q, got %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected || errObj.Kind != object.STEP_LIMIT_ERROR {
+				t.Errorf("wrong error for %q, expected %q, got %s: %q", tt.input, expected, errObj.KindName(), errObj.Message)
+			}
+		}
+	}
+}
+
+func TestPutsOutput(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedOutput string
+	}{
+		{`puts("hello", "world!")`, "hello\nworld!\n"},
+		{`puts()`, ""},
+		{`for (x in [1, 2]) { puts(x * 2) }`, "2\n4\n"},
+		{`fn log(msg) { puts("[log] ${msg}") }; [1, 2].map(fn(x) { log(x) })`, "[log] 1\n[log] 2\n"},
+	}
+
+	for _, tt := range tests {
+		_, output := testEvalWithInput(tt.input, "")
+
+		if output != tt.expectedOutput {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expectedOutput, output)
+		}
+	}
+}
+
+// Values that contain themselves print the repeat as [...] / {...} instead of recursing forever
+func TestSelfReferencingValues(t *testing.T) {
+	tests := []struct {
+		input          string
+		expected       string
+		expectedOutput string
+	}{
+		{`let a = [1]; a[0] = a; puts(a); "${a}"`, "[[...]]", "[[...]]\n"},
+		{`let h = {}; h["self"] = h; "${h}"`, `{"self" : "{...}"}`, ""},
+		{`let a = [1, 2]; let h = {"a": a}; a[1] = h; "${h}"`, `{"a" : "[1, {...}]"}`, ""},
+		// the same array twice isn't a cycle
+		{`let a = [1]; let b = [a, a]; "${b}"`, "[[1], [1]]", ""},
+	}
+
+	for _, tt := range tests {
+		evaluated, output := testEvalWithInput(tt.input, "")
+
+		testStringObject(t, evaluated, tt.expected)
+
+		if output != tt.expectedOutput {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expectedOutput, output)
+		}
+	}
+}
+
+func TestGets(t *testing.T) {
+	tests := []struct {
+		input          string
+		stdin          string
+		expected       interface{}
+		expectedOutput string
+	}{
+		{`gets()`, "hello\nworld\n", "hello", ""},
+		{`gets(); gets()`, "hello\nworld\n", "world", ""},
+		{`readLine()`, "hello\r\n", "hello", ""},
+		// the last line doesn't need a line break
+		{`gets(); gets()`, "hello\nworld", "world", ""},
+		{`gets()`, "", nil, ""},
+		{`gets(); gets()`, "hello\n", nil, ""},
+		{`gets("name: ")`, "Boar\n", "Boar", "name: "},
+		{`let name = readLine("name: "); puts("hi ${name}"); name`, "Boar\n", "Boar", "name: hi Boar\n"},
+		{`let total = [0]; let line = gets(); while (line) { total[0] = total[0] + len(line); line = gets() }; total[0]`, "ab\ncde\n", 5, ""},
+		{`gets(1)`, "", "argument to `gets` must be STRING, got INTEGER", ""},
+		{`gets("a", "b")`, "", "wrong number of arguments passed to gets. Got 2 wanted 0 or 1", ""},
+	}
+
+	for _, tt := range tests {
+		evaluated, output := testEvalWithInput(tt.input, tt.stdin)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			if err, ok := evaluated.(*object.Error); ok {
+				if err.Message != expected {
+					t.Errorf("wrong error message for %q. expected=%q, got=%q", tt.input, expected, err.Message)
+				}
+				continue
+			}
+			testStringObject(t, evaluated, expected)
+		}
+
+		if output != tt.expectedOutput {
+			t.Errorf("wrong output for %q. expected=%q, got=%q", tt.input, tt.expectedOutput, output)
+		}
+	}
+}
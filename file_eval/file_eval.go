@@ -5,6 +5,7 @@ import (
 	"boar/lexer"
 	"boar/object"
 	"boar/parser"
+	"boar/resolver"
 	"boar/setuphelpers"
 	"io"
 	"io/ioutil"
@@ -15,18 +16,25 @@ import (
 
 func EvaluateFile(in io.Reader, out io.Writer, filePath string) {
 	env := object.NewEnvironment()
+	env.SetContext(object.NewContext(in, out, os.Stderr))
 	setuphelpers.LoadBuiltInMethods(env)
 
 	fileContent := locateFile(filePath)
 	// pass it through the lexer
-	l := lexer.New(fileContent)
+	l := lexer.NewWithFile(filePath, fileContent)
 	// pass lexer generated tokens to the parser
 	p := parser.New(l)
 	// parse the program
 	program := p.ParseProgram()
 
 	if len(p.Errors()) != 0 {
-		setuphelpers.PrintParserErrors(out, p.Errors())
+		setuphelpers.PrintParserErrors(out, fileContent, p.Diagnostics())
+		return
+	}
+
+	// undefined variables
+	if diagnostics := resolver.Resolve(program, env); len(diagnostics) != 0 {
+		setuphelpers.PrintParserErrors(out, fileContent, diagnostics)
 		return
 	}
 
@@ -34,7 +42,7 @@ func EvaluateFile(in io.Reader, out io.Writer, filePath string) {
 	evaluated := evaluator.Eval(program, env)
 	if evaluated != nil {
 		// apply syntax highlighting
-		io.WriteString(out, evaluated.Inspect())
+		io.WriteString(out, setuphelpers.FormatResult(evaluated))
 		io.WriteString(out, "\n")
 	}
 
@@ -1,48 +1,110 @@
 package lexer
 
 import (
+	"boar/diagnostic"
 	"boar/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 //Struct to read "tokens"
 type Lexer struct {
 	input string // the entire string of characters that we've captured / 'source code'
-	// current position in input (points to current character) points to the char in the input that corresponds to ch byte.
+	// current position in input (points to current character) points to the byte in the input where the ch rune starts.
 	position int
 	// current position in reading (after current character), points to the "next" character in the input
 	readPosition int
-	//current char under examination
-	ch byte
+	//current char under examination (a unicode code point, which can take up several bytes of the input)
+	ch rune
+	// name of the file being lexed (empty for the REPL, tests, etc)
+	file string
+	// line and column of the current character (both start at 1)
+	line   int
+	column int
+	// when true comments are returned as token.COMMENT instead of being skipped
+	emitComments bool
+	// problems found while lexing (unterminated comments, etc)
+	diagnostics []*diagnostic.Diagnostic
+	// where the string currently being read started (its opening quote)
+	stringStart token.Position
+	// the ${ ... } interpolations we're currently inside of, innermost last
+	interpolations []interpolation
+	// diagnostics of every token.ILLEGAL token, by the offset of the token
+	illegalTokens map[int][]*diagnostic.Diagnostic
+}
+
+// An interpolation (${ ... }) inside of a string that is still being lexed
+type interpolation struct {
+	// number of unclosed { inside of the interpolation, the } that closes it is the one found at depth 0
+	depth int
+	// the opening quote of the string the interpolation belongs to
+	stringStart token.Position
 }
 
 //Return a reference to a lexer struct value
 func New(input string) *Lexer {
 	// point to the new Lexer struct we're creating
 	// initialize that struct with the source code we want to tokenize / lex
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	// Lets make sure that our *Lexer is in a fully working state before anyone calls NextToken()
 	// with l.ch, l.position and l.readPosition already initialized.
 	l.readChar()
 	return l //return the address of the new Lexer
 }
 
+// Same as New, but every token position will also carry the name of the file it came from
+func NewWithFile(file string, input string) *Lexer {
+	l := New(input)
+	l.file = file
+	return l
+}
+
+// Whether comments should be returned as token.COMMENT (for formatters, doc tools, etc) or skipped (the default)
+func (l *Lexer) EmitComments(emit bool) {
+	l.emitComments = emit
+}
+
+// Returns the problems found while lexing so far.
+// Every one of them also produces a token.ILLEGAL token covering it.
+func (l *Lexer) Diagnostics() []*diagnostic.Diagnostic {
+	return l.diagnostics
+}
+
+// Returns the diagnostics reported while lexing the given token.ILLEGAL token (a string can have several bad escapes)
+func (l *Lexer) DiagnosticsFor(tok token.Token) []*diagnostic.Diagnostic {
+	return l.illegalTokens[tok.Pos.Offset]
+}
+
 /**
 	- give us the next char
 	- advances our position pointers used on the input string
 **/
 func (l *Lexer) readChar() {
+	// Keep track of the line and column before we move onto the next character
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 1
+	} else {
+		l.column += 1
+	}
+
+	// how many bytes the next character takes up
+	width := 1
+
 	// If we've reached the end of the input
 	if l.readPosition >= len(l.input) {
 		// Set ch to 0 (ASCII for "NUL" char. Signifies nothing read or EOF)
 		l.ch = 0
 	} else {
-		// Else, set l.ch to the next character
-		l.ch = l.input[l.readPosition] //Access the specific char in the string using the current read position
+		// Else, decode the next character (1 to 4 bytes) starting at the current read position
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	// Move the current position in input to the next character
 	l.position = l.readPosition
-	// Increment so we point to the next char
-	l.readPosition += 1
+	// Move past the bytes of the current char so we point to the next char
+	l.readPosition += width
 }
 
 /**
@@ -53,10 +115,35 @@ func (l *Lexer) readChar() {
 	depending on which character it is.
 **/
 func (l *Lexer) NextToken() token.Token {
+	reported := len(l.diagnostics)
+	tok := l.nextToken()
+
+	// the lexer is on the character right after the token, at EOF there's nothing to point past
+	if tok.Type == token.EOF {
+		tok.End = tok.Pos
+	} else {
+		tok.End = l.currentPosition()
+	}
+
+	// keep track of which diagnostics belong to this token so the parser can report them when it runs into it
+	if tok.Type == token.ILLEGAL && len(l.diagnostics) > reported {
+		if l.illegalTokens == nil {
+			l.illegalTokens = make(map[int][]*diagnostic.Diagnostic)
+		}
+		l.illegalTokens[tok.Pos.Offset] = l.diagnostics[reported:len(l.diagnostics):len(l.diagnostics)]
+	}
+
+	return tok
+}
+
+func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
 	// Ignore any whitespace found in the current char, (Boar Lang doesn't add meaning to white spaces)
 	l.skipWhitespace()
 
+	// The token starts at the current char, save its position before we start reading it
+	pos := l.currentPosition()
+
 	// Read the char the lexer is currently on
 	// tokenize it (figure out what it is)
 	switch l.ch {
@@ -68,6 +155,8 @@ func (l *Lexer) NextToken() token.Token {
 			// move to the next character (the other equal sign)
 			l.readChar()
 			tok = token.Token{Type: token.EQ, Literal: string(ch) + string(l.ch)}
+		} else if l.peekChar() == '>' {
+			tok = l.newTwoCharToken(token.ARROW)
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
@@ -86,13 +175,52 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
+		// '//' and '/*' start comments
+		if l.peekChar() == '/' || l.peekChar() == '*' {
+			tok = l.readComment()
+			tok.Pos = pos
+
+			if tok.Type == token.COMMENT && !l.emitComments {
+				// skip over the comment like we do with whitespace
+				return l.nextToken()
+			}
+
+			return tok
+		}
 		tok = newToken(token.SLASH, l.ch)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '*' {
+			tok = l.newTwoCharToken(token.POWER)
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '%':
+		tok = newToken(token.PERCENT, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.LT_EQ)
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.GT_EQ)
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		// there's no single & operator (yet)
+		if l.peekChar() == '&' {
+			tok = l.newTwoCharToken(token.AND)
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.newTwoCharToken(token.OR)
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ',':
@@ -102,12 +230,33 @@ func (l *Lexer) NextToken() token.Token {
 	case ')':
 		tok = newToken(token.RPAREN, l.ch)
 	case '{':
+		if len(l.interpolations) > 0 {
+			l.interpolations[len(l.interpolations)-1].depth++
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
+		// closing an interpolation, go back to reading the string it belongs to
+		if n := len(l.interpolations); n > 0 && l.interpolations[n-1].depth == 0 {
+			tok = l.readStringContinuation()
+			break
+		}
+
+		if len(l.interpolations) > 0 {
+			l.interpolations[len(l.interpolations)-1].depth--
+		}
 		tok = newToken(token.RBRACE, l.ch)
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		l.stringStart = pos
+		value, ok, interpolated := l.readString()
+		tok = l.stringToken(pos.Offset, value, ok)
+
+		if tok.Type == token.STRING && interpolated {
+			tok.Type = token.INTERP_START
+			l.interpolations = append(l.interpolations, interpolation{stringStart: pos})
+		}
+	case '`':
+		value, ok := l.readRawString()
+		tok = l.stringToken(pos.Offset, value, ok)
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
@@ -115,6 +264,20 @@ func (l *Lexer) NextToken() token.Token {
 	case ':':
 		tok = newToken(token.COLON, l.ch)
 	case '.':
+		// .5 is a float, arr.pop() and hash.5 are not
+		if isDigit(l.peekChar()) && !l.followsOperand() {
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Pos = pos
+			return tok
+		}
+
+		if l.peekChar() == '.' && l.peekCharAt(2) == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+			break
+		}
+
 		tok = newToken(token.DOT, l.ch)
 	case 0:
 		// reached EOF
@@ -126,6 +289,7 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Pos = pos
 			/**
 				The early exit here is necessary because when calling readIdentifier() we call readChar()
 				repeatedly and advance our readPosition and position fields past the last character of the current
@@ -135,8 +299,8 @@ func (l *Lexer) NextToken() token.Token {
 			**/
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Literal, tok.Type = l.readNumber()
+			tok.Pos = pos
 			return tok
 		} else {
 			// If we cant identify the char, consider it illegal.
@@ -146,17 +310,24 @@ func (l *Lexer) NextToken() token.Token {
 	// Read next character so l.ch is already updated when we call this method again.
 	l.readChar()
 
+	tok.Pos = pos
+
 	return tok
 }
 
+// Returns the position of the character currently under examination (l.ch)
+func (l *Lexer) currentPosition() token.Position {
+	return token.Position{File: l.file, Line: l.line, Column: l.column, Offset: l.position}
+}
+
 /**
   Reads the identifier and advances the lexer's position until it encounters a non-letter character.
 **/
 func (l *Lexer) readIdentifier() string {
 	// position where we first encountered the potential identifier
 	position := l.position
-	// while the current character is a letter lets read each character and advance our lexers position
-	for isLetter(l.ch) {
+	// while the current character can be part of an identifier lets read each character and advance our lexers position
+	for isIdentifierPart(l.ch) {
 		l.readChar()
 	}
 
@@ -174,22 +345,44 @@ note:
 - because we'll consider _ as a letter we can allow it in identifiers and keywords.
 - this means we can use variables with names like foo_bar
 - we can also sneak in other identifiers like ! and ? here too.
+- any unicode letter works too: café, 名前, etc
 **/
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch >= utf8.RuneSelf && unicode.IsLetter(ch)
+}
+
+/**
+Characters after the first one of an identifier: letters, digits and combining marks.
+Marks are needed for scripts like Devanagari (नाम) and for decomposed letters (e + U+0301 for é),
+digits are fine since an identifier can't start with one: x2 is an identifier, 2x is still a number then x
+**/
+func isIdentifierPart(ch rune) bool {
+	return isLetter(ch) || isDigit(ch) || ch >= utf8.RuneSelf && (unicode.IsMark(ch) || unicode.IsDigit(ch))
 }
 
 /**
 params:
 - tokenType
-- character byte
+- character rune
 
 returns: Token
 **/
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
+/**
+Same as newToken but for operators made out of two characters (<=, &&, **, etc).
+Moves the lexer onto the second character, NextToken() will move past it.
+**/
+func (l *Lexer) newTwoCharToken(tokenType token.TokenType) token.Token {
+	// save the current char so we don't lose it calling l.readChar()
+	ch := l.ch
+	l.readChar()
+
+	return token.Token{Type: tokenType, Literal: string(ch) + string(l.ch)}
+}
+
 // Skips any whitespace so our lexer can ignore it.
 func (l *Lexer) skipWhitespace() {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
@@ -199,17 +392,39 @@ func (l *Lexer) skipWhitespace() {
 }
 
 /**
+Reads integers (123) and floats (1.5, .5, 1e-3, 2.5E+10)
+
 note:
 
-- We only read ints here, not floats, hex notation, octal, etc.
+- No hex notation, octal, etc.
 This is to keep things simple...for now :)
+- The '.' has to be followed by a digit to be part of a float, so 1.foo() is still INT, DOT, IDENT
 **/
-func (l *Lexer) readNumber() string {
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position
-	// if the character is a digit
-	for isDigit(l.ch) {
-		// update the position of the lexer
+	tokenType := token.TokenType(token.INT)
+
+	l.readDigits()
+
+	// fractional part: 1.5, .5
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
 		l.readChar()
+		l.readDigits()
+	}
+
+	// exponent: 1e10, 1e-3, 1.5E+2
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if isDigit(next) || ((next == '+' || next == '-') && isDigit(l.peekCharAt(2))) {
+			tokenType = token.FLOAT
+			// skip over the 'e' and the sign
+			l.readChar()
+			if next == '+' || next == '-' {
+				l.readChar()
+			}
+			l.readDigits()
+		}
 	}
 
 	// return the subset of the string at these positions
@@ -217,37 +432,339 @@ func (l *Lexer) readNumber() string {
 		position being the index of when we first found our number
 		l.position being the index of when its no longer a number
 	*/
-	return l.input[position:l.position]
+	return l.input[position:l.position], tokenType
 }
 
-func isDigit(ch byte) bool {
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) {
+		// update the position of the lexer
+		l.readChar()
+	}
+}
+
+/**
+Whether the character right before the current one ends an operand (x, 5, "a", ), ], }),
+in that case a '.' is a method call / property access and not the start of a float.
+**/
+func (l *Lexer) followsOperand() bool {
+	if l.position == 0 {
+		return false
+	}
+
+	prev, _ := utf8.DecodeLastRuneInString(l.input[:l.position])
+
+	return isIdentifierPart(prev) || prev == ')' || prev == ']' || prev == '}' || prev == '"'
+}
+
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+// Same as peekChar but looks n characters ahead (peekCharAt(1) == peekChar())
+func (l *Lexer) peekCharAt(n int) rune {
+	idx := l.readPosition
+	ch := rune(0)
+
+	for ; n > 0; n-- {
+		if idx >= len(l.input) {
+			return 0
+		}
+
+		var width int
+		ch, width = utf8.DecodeRuneInString(l.input[idx:])
+		idx += width
+	}
+
+	return ch
+}
+
 // Allows us to look ahead in the input but not move around it.
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	// if we've reached EOF, return NULL
 	if l.readPosition >= len(l.input) {
 		return 0
 	} else {
-		return l.input[l.readPosition]
+		ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+		return ch
 	}
 }
 
-func (l *Lexer) readString() string {
-	// skip over the " char
-	position := l.position + 1
+/**
+Builds the token for a string that was just read by readString / readRawString.
+Strings with problems (bad escapes, no closing quote) become token.ILLEGAL tokens,
+their literal is their source code (starting at start).
+**/
+func (l *Lexer) stringToken(start int, value string, ok bool) token.Token {
+	if ok {
+		return token.Token{Type: token.STRING, Literal: value}
+	}
+
+	// whatever interpolation we were in can't be closed anymore
+	l.interpolations = nil
+
+	end := l.position + 1
+	if end > len(l.input) {
+		end = len(l.input)
+	}
+
+	return token.Token{Type: token.ILLEGAL, Literal: l.input[start:end]}
+}
+
+/**
+Reads a "double quoted" string, the current char should be the opening quote.
+
+Escape sequences:
+- \n, \t, \r, \0, \\, \"
+- \xHH: a byte written in hex (\x41 == "A")
+- \uHHHH and \u{H...}: a unicode code point (\u00e9 == \u{e9} == "é")
+- \$: a literal $, so "\${x}" isn't interpolated
+
+Interpolations: reading stops at a ${, the value returned is the text before it and interpolated is true.
+The tokens of the expression come next, the } closing it continues the string (see readStringContinuation).
+
+Returns the value of the string and whether it was valid.
+When this returns l.ch is the closing quote, the { of ${ or EOF.
+**/
+func (l *Lexer) readString() (value string, valid bool, interpolated bool) {
+	var out strings.Builder
+	valid = true
 
 	// read characters until we reach the end of the string
 	for {
 		l.readChar()
 
-		if l.ch == '"' || l.ch == 0 {
-			break
+		switch l.ch {
+		case '"':
+			return out.String(), valid, false
+		case 0:
+			l.unterminatedString()
+			return out.String(), false, false
+		case '$':
+			if l.peekChar() != '{' {
+				out.WriteRune(l.ch)
+				continue
+			}
+			// move onto the {
+			l.readChar()
+			return out.String(), valid, true
+		case '\\':
+			if !l.readEscape(&out) {
+				valid = false
+			}
+		default:
+			// copy the bytes of the char as they are in the source (keeps invalid UTF-8 untouched)
+			out.WriteString(l.input[l.position:l.readPosition])
 		}
 	}
-	// return chars that are in between the quotes
-	return l.input[position:l.position]
+}
+
+/**
+Reads the rest of an interpolated string after the } closing an interpolation (the current char).
+Returns INTERP_MID if another ${ follows, INTERP_END once the string is closed.
+**/
+func (l *Lexer) readStringContinuation() token.Token {
+	n := len(l.interpolations) - 1
+	start := l.position
+	l.stringStart = l.interpolations[n].stringStart
+
+	value, ok, interpolated := l.readString()
+	tok := l.stringToken(start, value, ok)
+
+	if !ok {
+		return tok
+	}
+
+	if interpolated {
+		tok.Type = token.INTERP_MID
+	} else {
+		tok.Type = token.INTERP_END
+		l.interpolations = l.interpolations[:n]
+	}
+
+	return tok
+}
+
+/**
+Reads a `raw string`, the current char should be the opening backtick.
+Nothing is escaped in raw strings and they can span several lines, handy for templates and multi-line text.
+**/
+func (l *Lexer) readRawString() (string, bool) {
+	l.stringStart = l.currentPosition()
+	position := l.position + 1
+
+	// raw strings never interpolate, ${ is just text
+
+	for {
+		l.readChar()
+
+		if l.ch == '`' {
+			return l.input[position:l.position], true
+		}
+
+		if l.ch == 0 {
+			l.unterminatedString()
+			return l.input[position:l.position], false
+		}
+	}
+}
+
+func (l *Lexer) unterminatedString() {
+	quote := l.input[l.stringStart.Offset]
+	tok := token.Token{Type: token.ILLEGAL, Literal: string(quote), Pos: l.stringStart}
+
+	l.diagnostics = append(l.diagnostics, diagnostic.NewError(diagnostic.UNTERMINATED_STRING, tok, "unterminated string literal").
+		WithHint("close the string with %c", quote))
+}
+
+/**
+Reads an escape sequence, the current char should be the backslash.
+Writes the escaped value into out and returns false (recording a diagnostic) when the escape isn't valid.
+When this returns l.ch is the last character of the escape sequence.
+**/
+func (l *Lexer) readEscape(out *strings.Builder) bool {
+	start := l.currentPosition()
+	l.readChar()
+
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '0':
+		out.WriteByte(0)
+	case '\\', '"', '$':
+		out.WriteRune(l.ch)
+	case 'x':
+		value, ok := l.readHexDigits(2)
+		if !ok {
+			return l.invalidEscape(start, "\\x must be followed by 2 hex digits")
+		}
+		out.WriteByte(byte(value))
+	case 'u':
+		var value uint64
+		var ok bool
+
+		// \u{1F417}
+		if l.peekChar() == '{' {
+			l.readChar()
+			value, ok = l.readHexDigits(-1)
+			if !ok || l.peekChar() != '}' {
+				return l.invalidEscape(start, "\\u{...} must contain 1 to 6 hex digits")
+			}
+			l.readChar()
+		} else if value, ok = l.readHexDigits(4); !ok {
+			return l.invalidEscape(start, "\\u must be followed by 4 hex digits or {...}")
+		}
+
+		if value > 0x10FFFF || (value >= 0xD800 && value <= 0xDFFF) {
+			return l.invalidEscape(start, "\\u escape is not a valid unicode code point")
+		}
+		out.WriteRune(rune(value))
+	case 0:
+		// the string is never closed, readString reports that
+		return false
+	default:
+		return l.invalidEscape(start, "unknown escape sequence \\%c", l.ch)
+	}
+
+	return true
+}
+
+/**
+Reads exactly n hex digits (or 1 to 6 of them when n is -1), l.ch ends on the last digit.
+Nothing is consumed if the digits aren't there.
+**/
+func (l *Lexer) readHexDigits(n int) (uint64, bool) {
+	count := 0
+	for isHexDigit(l.peekCharAt(count + 1)) {
+		count++
+	}
+
+	if (n == -1 && (count == 0 || count > 6)) || (n != -1 && count < n) {
+		return 0, false
+	}
+
+	if n != -1 {
+		count = n
+	}
+
+	digits := l.input[l.readPosition : l.readPosition+count]
+	for i := 0; i < count; i++ {
+		l.readChar()
+	}
+
+	value, err := strconv.ParseUint(digits, 16, 64)
+
+	return value, err == nil
+}
+
+func (l *Lexer) invalidEscape(start token.Position, format string, a ...interface{}) bool {
+	literal := l.input[start.Offset : l.position+1]
+	tok := token.Token{Type: token.ILLEGAL, Literal: literal, Pos: start}
+
+	l.diagnostics = append(l.diagnostics, diagnostic.NewError(diagnostic.INVALID_ESCAPE, tok, format, a...).
+		WithHint("escape the backslash (\\\\) to get a literal \\, or use a `raw string`"))
+
+	return false
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+/**
+Reads a comment, the current char should be the '/' starting it.
+
+- Line comments start with '//' and run until the end of the line, the line break isn't part of the comment.
+- Block comments start with '/' + '*' and end with '*' + '/', they can span several lines and can be nested.
+- A block comment that never gets closed returns a token.ILLEGAL token and records a diagnostic.
+
+When this returns l.ch is the character right after the comment.
+**/
+func (l *Lexer) readComment() token.Token {
+	position := l.position
+	start := l.currentPosition()
+
+	// line comment
+	if l.peekChar() == '/' {
+		for l.ch != '\n' && l.ch != 0 {
+			l.readChar()
+		}
+
+		return token.Token{Type: token.COMMENT, Literal: l.input[position:l.position]}
+	}
+
+	// block comment, move past the opening /*
+	l.readChar()
+	l.readChar()
+
+	depth := 1
+
+	for depth > 0 {
+		switch {
+		case l.ch == 0:
+			// reached EOF before closing every block
+			literal := l.input[position:l.position]
+			tok := token.Token{Type: token.ILLEGAL, Literal: literal, Pos: start}
+
+			l.diagnostics = append(l.diagnostics, diagnostic.NewError(diagnostic.UNTERMINATED_COMMENT, tok, "unterminated block comment").
+				WithHint("close the comment with */"))
+
+			return tok
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+		}
+
+		l.readChar()
+	}
+
+	return token.Token{Type: token.COMMENT, Literal: l.input[position:l.position]}
 }
 
 /**
@@ -282,11 +799,10 @@ result would look something like:
 
 Other:
 ------------------------------
-- The lexer only supports ASCII characters instead of the full Unicode range.
-- This lets us keep things simple.
-- In order to fully support Unicode and UTF-8 we would need to:
-  - change l.ch from a byte to a rune
-	- change the way we read the next characters, since they would be multiple bytes not.
-	- Using l.input[l.readPosition] wouldn't work anymore..
+- The lexer supports the full Unicode range, the input is read as UTF-8:
+  - l.ch is a rune (a code point) instead of a byte
+	- readChar() decodes the next character, which can take up 1 to 4 bytes of the input.
+	- l.position / l.readPosition (and token offsets) are still byte offsets into the input,
+	  while columns count characters.
 
 	**/
@@ -26,7 +26,7 @@ func TestNextToken(t *testing.T) {
 
 	let result = add(five, ten);
 
-	!-/*5;
+	!-/ *5;
 	5 < 10 > 5;
 
 	if (5 < 10) {
@@ -192,3 +192,483 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestOperators(t *testing.T) {
+	input := `a <= b >= c % d ** e && f || g * h < i > j in match => = ...rest try catch finally throw const`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "d"},
+		{token.POWER, "**"},
+		{token.IDENT, "e"},
+		{token.AND, "&&"},
+		{token.IDENT, "f"},
+		{token.OR, "||"},
+		{token.IDENT, "g"},
+		{token.ASTERISK, "*"},
+		{token.IDENT, "h"},
+		{token.LT, "<"},
+		{token.IDENT, "i"},
+		{token.GT, ">"},
+		{token.IDENT, "j"},
+		{token.IN, "in"},
+		{token.MATCH, "match"},
+		{token.ARROW, "=>"},
+		{token.ASSIGN, "="},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.TRY, "try"},
+		{token.CATCH, "catch"},
+		{token.FINALLY, "finally"},
+		{token.THROW, "throw"},
+		{token.CONST, "const"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+let y = "foo";
+  x + y`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+		expectedOffset  int
+	}{
+		{"let", 1, 1, 0},
+		{"x", 1, 5, 4},
+		{"=", 1, 7, 6},
+		{"5", 1, 9, 8},
+		{";", 1, 10, 9},
+		{"let", 2, 1, 11},
+		{"y", 2, 5, 15},
+		{"=", 2, 7, 17},
+		{"foo", 2, 9, 19},
+		{";", 2, 14, 24},
+		{"x", 3, 3, 28},
+		{"+", 3, 5, 30},
+		{"y", 3, 7, 32},
+		{"", 3, 8, 33},
+	}
+
+	l := NewWithFile("test.br", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.File != "test.br" {
+			t.Fatalf("tests[%d] - file wrong. expected=%q, got %q", i, "test.br", tok.Pos.File)
+		}
+
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got %d:%d", i, tt.expectedLine, tt.expectedColumn, tok.Pos.Line, tok.Pos.Column)
+		}
+
+		if tok.Pos.Offset != tt.expectedOffset {
+			t.Fatalf("tests[%d] - offset wrong. expected=%d, got %d", i, tt.expectedOffset, tok.Pos.Offset)
+		}
+	}
+}
+
+// End points right after the token in the source, even when the literal is shorter (escapes, quotes, ${)
+func TestTokenEnd(t *testing.T) {
+	input := `"a\tb" "x${y}z" ` + "`raw\nline`" + ` name`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+		expectedOffset int
+	}{
+		{token.STRING, 1, 7, 6},
+		{token.INTERP_START, 1, 12, 11},
+		{token.IDENT, 1, 13, 12},
+		{token.INTERP_END, 1, 16, 15},
+		{token.STRING, 2, 6, 26},
+		{token.IDENT, 2, 11, 31},
+		{token.EOF, 2, 11, 31},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.End.Line != tt.expectedLine || tok.End.Column != tt.expectedColumn || tok.End.Offset != tt.expectedOffset {
+			t.Fatalf("tests[%d] - end wrong. expected=%d:%d (offset %d), got %d:%d (offset %d)", i, tt.expectedLine, tt.expectedColumn, tt.expectedOffset, tok.End.Line, tok.End.Column, tok.End.Offset)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// a line comment
+let x = 5; // trailing comment
+/* a block
+   comment */
+x / 2;
+/* outer /* nested */ still a comment */ x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	if len(l.Diagnostics()) != 0 {
+		t.Fatalf("expected no diagnostics, got %d", len(l.Diagnostics()))
+	}
+}
+
+func TestEmitComments(t *testing.T) {
+	input := `// first
+x /* second */ y`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{token.COMMENT, "// first", 1, 1},
+		{token.IDENT, "x", 2, 1},
+		{token.COMMENT, "/* second */", 2, 3},
+		{token.IDENT, "y", 2, 16},
+		{token.EOF, "", 2, 17},
+	}
+
+	l := New(input)
+	l.EmitComments(true)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got %d:%d", i, tt.expectedLine, tt.expectedColumn, tok.Pos.Line, tok.Pos.Column)
+		}
+	}
+}
+
+func TestUnterminatedComment(t *testing.T) {
+	input := `let x = 1;
+/* never /* closed */`
+
+	l := New(input)
+
+	var tok token.Token
+	for tok = l.NextToken(); tok.Type != token.ILLEGAL && tok.Type != token.EOF; tok = l.NextToken() {
+	}
+
+	if tok.Type != token.ILLEGAL {
+		t.Fatalf("expected an ILLEGAL token, got %q", tok.Type)
+	}
+
+	if tok.Pos.Line != 2 || tok.Pos.Column != 1 {
+		t.Fatalf("position wrong. expected=2:1, got %d:%d", tok.Pos.Line, tok.Pos.Column)
+	}
+
+	if len(l.Diagnostics()) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(l.Diagnostics()))
+	}
+
+	d := l.Diagnostics()[0]
+	if d.String() != "2:1: unterminated block comment" {
+		t.Fatalf("diagnostic wrong. got %q", d.String())
+	}
+
+	if tok = l.NextToken(); tok.Type != token.EOF {
+		t.Fatalf("expected EOF after the unterminated comment, got %q", tok.Type)
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `5 1.5 .5 1e-3 2.5E+10 3e2 arr.pop 1.foo x.5 (.5) 1e`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "5"},
+		{token.FLOAT, "1.5"},
+		{token.FLOAT, ".5"},
+		{token.FLOAT, "1e-3"},
+		{token.FLOAT, "2.5E+10"},
+		{token.FLOAT, "3e2"},
+		{token.IDENT, "arr"},
+		{token.DOT, "."},
+		{token.IDENT, "pop"},
+		{token.INT, "1"},
+		{token.DOT, "."},
+		{token.IDENT, "foo"},
+		{token.IDENT, "x"},
+		{token.DOT, "."},
+		{token.INT, "5"},
+		{token.LPAREN, "("},
+		{token.FLOAT, ".5"},
+		{token.RPAREN, ")"},
+		{token.INT, "1"},
+		{token.IDENT, "e"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\nb"`, "a\nb"},
+		{`"tab\there"`, "tab\there"},
+		{`"\r\0"`, "\r\x00"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"\x41\x62"`, "Ab"},
+		{`"café"`, "café"},
+		{`"\u{1F417}"`, "🐗"},
+		{"`raw \\n string`", `raw \n string`},
+		{"`multi\nline`", "multi\nline"},
+		{"`no \"escapes\" here`", `no "escapes" here`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+
+		if tok.Type != token.STRING {
+			t.Fatalf("tokentype wrong for %s. expected=%q, got %q", tt.input, token.STRING, tok.Type)
+		}
+
+		if tok.Literal != tt.expected {
+			t.Errorf("literal wrong for %s. expected=%q, got %q", tt.input, tt.expected, tok.Literal)
+		}
+
+		if tok = l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("expected EOF after %s, got %q", tt.input, tok.Type)
+		}
+	}
+}
+
+func TestInvalidStrings(t *testing.T) {
+	tests := []struct {
+		input               string
+		expectedDiagnostics []string
+	}{
+		{`"bad \q escape"`, []string{"1:6: unknown escape sequence \\q"}},
+		{`"\x4" "\u12" "\u{}"`, []string{
+			"1:2: \\x must be followed by 2 hex digits",
+			"1:8: \\u must be followed by 4 hex digits or {...}",
+			"1:15: \\u{...} must contain 1 to 6 hex digits",
+		}},
+		{`"\uD800"`, []string{"1:2: \\u escape is not a valid unicode code point"}},
+		{"let x = \"never closed;\nlet y = 2;", []string{"1:9: unterminated string literal"}},
+		{"`raw\nnever closed", []string{"1:1: unterminated string literal"}},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+
+		illegal := 0
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+			if tok.Type == token.ILLEGAL {
+				illegal++
+			}
+		}
+
+		if illegal == 0 {
+			t.Errorf("expected an ILLEGAL token for %q", tt.input)
+		}
+
+		diagnostics := l.Diagnostics()
+
+		if len(diagnostics) != len(tt.expectedDiagnostics) {
+			t.Errorf("wrong number of diagnostics for %q, expected %d, got %d", tt.input, len(tt.expectedDiagnostics), len(diagnostics))
+			continue
+		}
+
+		for i, expected := range tt.expectedDiagnostics {
+			if diagnostics[i].String() != expected {
+				t.Errorf("wrong diagnostic for %q, expected %q, got %q", tt.input, expected, diagnostics[i].String())
+			}
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	input := `"Hello ${name}, you have ${len(items)} items" "${ {"a": 1}["a"] }" "a ${"b ${c} d"} e" "\${x} $y"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INTERP_START, "Hello "},
+		{token.IDENT, "name"},
+		{token.INTERP_MID, ", you have "},
+		{token.IDENT, "len"},
+		{token.LPAREN, "("},
+		{token.IDENT, "items"},
+		{token.RPAREN, ")"},
+		{token.INTERP_END, " items"},
+		// braces inside of the interpolation don't close it
+		{token.INTERP_START, ""},
+		{token.LBRACE, "{"},
+		{token.STRING, "a"},
+		{token.COLON, ":"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "a"},
+		{token.RBRACKET, "]"},
+		{token.INTERP_END, ""},
+		// strings inside of interpolations can interpolate too
+		{token.INTERP_START, "a "},
+		{token.INTERP_START, "b "},
+		{token.IDENT, "c"},
+		{token.INTERP_END, " d"},
+		{token.INTERP_END, " e"},
+		// escaped / not followed by {
+		{token.STRING, "${x} $y"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestUnicodeSource(t *testing.T) {
+	input := `let café = "héllo 🐗";
+名前 + ñ
+नाम x2 cafe` + "\u0301"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+		expectedOffset  int
+	}{
+		{token.LET, "let", 1, 1, 0},
+		{token.IDENT, "café", 1, 5, 4},
+		{token.ASSIGN, "=", 1, 10, 10},
+		{token.STRING, "héllo 🐗", 1, 12, 12},
+		{token.SEMICOLON, ";", 1, 21, 25},
+		{token.IDENT, "名前", 2, 1, 27},
+		{token.PLUS, "+", 2, 4, 34},
+		{token.IDENT, "ñ", 2, 6, 36},
+		// combining marks and digits can follow the first letter
+		{token.IDENT, "नाम", 3, 1, 39},
+		{token.IDENT, "x2", 3, 5, 49},
+		{token.IDENT, "cafe\u0301", 3, 8, 52},
+		{token.EOF, "", 3, 13, 58},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("tests[%d] - position wrong. expected=%d:%d, got %d:%d", i, tt.expectedLine, tt.expectedColumn, tok.Pos.Line, tok.Pos.Column)
+		}
+
+		if tok.Pos.Offset != tt.expectedOffset {
+			t.Fatalf("tests[%d] - offset wrong. expected=%d, got %d", i, tt.expectedOffset, tok.Pos.Offset)
+		}
+	}
+}
@@ -1,2 +1 @@
-subjects=(parser lexer ast token evaluator object)
-for subject in "${subjects[@]}"; do /usr/local/go/bin/go test "./$subject"; done
\ No newline at end of file
+/usr/local/go/bin/go test ./...
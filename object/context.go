@@ -0,0 +1,34 @@
+package object
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+/**
+Where a running program reads its input from and writes its output to.
+
+Builtins like puts() and gets() go through the context instead of os.Stdout / os.Stdin,
+so a program embedding boar (or a test) can hand it whatever readers / writers it wants.
+It's stored in the global environment, see Environment.Context
+**/
+type Context struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Stdin  *bufio.Reader
+	// how many loop iterations and function calls a program can run, 0 means no limit. See Environment.Step
+	MaxSteps int
+}
+
+func NewContext(stdin io.Reader, stdout, stderr io.Writer) *Context {
+	return &Context{Stdin: bufio.NewReader(stdin), Stdout: stdout, Stderr: stderr}
+}
+
+// Shared by every environment that didn't get its own context, so they all read from the same buffered stdin
+var defaultContext = NewContext(os.Stdin, os.Stdout, os.Stderr)
+
+// The process' own stdin, stdout and stderr
+func DefaultContext() *Context {
+	return defaultContext
+}
@@ -1,22 +1,96 @@
 package object
 
+import "fmt"
+
 type Environment struct {
-	store map[string]Object
-	outer *Environment //outer scope
+	store map[string]Object // variables looked up by name: the globals
+	slots []Object          // variables the resolver gave a slot to: parameters, lets inside of functions, etc
+	outer *Environment      //outer scope
+	// the variables declared with const, they can't be assigned to or redeclared in the same scope.
+	// nil until the scope has a constant
+	consts     map[string]bool
+	constSlots map[int]string // slot => name of the constant
+	// the global scope every scope is enclosed by, it keeps track of the function calls in progress
+	// and holds the context (stdin, stdout, stderr) of the program
+	root      *Environment
+	callDepth int
+	steps     int
+	context   *Context
 }
 
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
-	return &Environment{store: s, outer: nil}
+	env := &Environment{store: s, outer: nil}
+	env.root = env
+	return env
 }
 
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	env.root = outer.root
 
 	return env
 }
 
+/**
+A local scope (a function call, a catch block, an iteration of a for-in loop) with room for size variables.
+
+The resolver already worked out which slot every local variable goes into (see ast.Binding),
+so reading one is an index into a slice instead of a map lookup in every scope up to the one that has it.
+**/
+func NewScope(outer *Environment, size int) *Environment {
+	return &Environment{slots: make([]Object, size), outer: outer, root: outer.root}
+}
+
+/**
+Function calls that haven't returned yet, shared by every scope of a program.
+
+Every call goes deeper into the Go stack, if we let a script recurse forever
+it would crash the whole process instead of giving back an error.
+EnterCall returns false once max calls are in progress, ExitCall has to be called for every successful EnterCall.
+**/
+func (e *Environment) EnterCall(max int) bool {
+	if e.root.callDepth >= max {
+		return false
+	}
+
+	e.root.callDepth++
+	return true
+}
+
+func (e *Environment) ExitCall() {
+	e.root.callDepth--
+}
+
+/**
+Counts a loop iteration or a function call, false once the context's MaxSteps were used up.
+The count is shared by every scope of a program, a script that never stops can still be stopped this way.
+**/
+func (e *Environment) Step() bool {
+	max := e.Context().MaxSteps
+	if max == 0 {
+		return true
+	}
+
+	e.root.steps++
+	return e.root.steps <= max
+}
+
+// The context of the program this environment belongs to, DefaultContext() unless SetContext was called
+func (e *Environment) Context() *Context {
+	if e.root.context == nil {
+		return DefaultContext()
+	}
+
+	return e.root.context
+}
+
+// Sets the context for the whole program (every scope shares the global environment's one)
+func (e *Environment) SetContext(ctx *Context) {
+	e.root.context = ctx
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
 	obj, ok := e.store[name]
 	/**
@@ -29,11 +103,107 @@ func (e *Environment) Get(name string) (Object, bool) {
 	return obj, ok
 }
 
+// Returns a TypeError instead of val when name is a constant of this scope
 func (e *Environment) Set(name string, val Object) Object {
+	if e.consts[name] {
+		return constantError(name)
+	}
+
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[name] = val
 	return val
 }
 
+// const name = val
+func (e *Environment) SetConst(name string, val Object) Object {
+	if result := e.Set(name, val); result != val {
+		return result
+	}
+
+	if e.consts == nil {
+		e.consts = make(map[string]bool)
+	}
+	e.consts[name] = true
+	return val
+}
+
+/**
+Updates an existing variable in the scope that has it: x = 5 inside of a closure changes the x it closed over.
+Set on the other hand always writes to the current scope (let).
+Returns false (and changes nothing) if no scope has the variable, a TypeError if it's a constant.
+**/
+func (e *Environment) Assign(name string, val Object) (Object, bool) {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return env.Set(name, val), true
+		}
+	}
+
+	return nil, false
+}
+
+// The variable in slot of the scope depth levels up, false if it hasn't been given a value yet
+func (e *Environment) GetAt(depth, slot int) (Object, bool) {
+	obj := e.ancestor(depth).slots[slot]
+	return obj, obj != nil
+}
+
+// Returns a TypeError instead of val when the slot holds a constant
+func (e *Environment) SetAt(depth, slot int, val Object) Object {
+	scope := e.ancestor(depth)
+
+	if name, ok := scope.constSlots[slot]; ok {
+		return constantError(name)
+	}
+
+	scope.slots[slot] = val
+	return val
+}
+
+// const name = val for a local variable, the name is only needed for error messages
+func (e *Environment) SetConstAt(depth, slot int, name string, val Object) Object {
+	if result := e.SetAt(depth, slot, val); result != val {
+		return result
+	}
+
+	scope := e.ancestor(depth)
+	if scope.constSlots == nil {
+		scope.constSlots = make(map[int]string)
+	}
+	scope.constSlots[slot] = name
+	return val
+}
+
+func (e *Environment) ancestor(depth int) *Environment {
+	env := e
+	for i := 0; i < depth; i++ {
+		env = env.outer
+	}
+	return env
+}
+
+// Globals live in the outermost environment, no matter how deep in the scopes we are
+func (e *Environment) GetGlobal(name string) (Object, bool) {
+	obj, ok := e.root.store[name]
+	return obj, ok
+}
+
+func (e *Environment) SetGlobal(name string, val Object) Object {
+	return e.root.Set(name, val)
+}
+
+func (e *Environment) SetGlobalConst(name string, val Object) Object {
+	return e.root.SetConst(name, val)
+}
+
+// Whether a global is defined, the resolver needs to know to tell which identifiers are undefined
+func (e *Environment) HasGlobal(name string) bool {
+	_, ok := e.root.store[name]
+	return ok
+}
+
 /**
 dev notes:
 - we need to preserve the bindings (let x = 1, let i = fn(){}) while at the same time
@@ -50,3 +220,7 @@ associated with the given name, it should call the Get method of the enclosing e
 
 
 **/
+
+func constantError(name string) *Error {
+	return &Error{Kind: TYPE_ERROR, Message: fmt.Sprintf("cannot assign to constant %s", name)}
+}
@@ -0,0 +1,145 @@
+package object
+
+import (
+	"fmt"
+	"math"
+)
+
+/**
+Iteration protocol, used by for (x in ...) loops.
+
+Any collection can be looped over by implementing Iterable:
+- Iter() returns a fresh Iterator every time it's called, so the same collection can be looped over several times.
+- Next() returns the next key / value pair, ok is false once there's nothing left.
+
+What the loop variables get bound to:
+- for (k, v in obj): the key and the value
+- for (x in obj): the value, except for hashes where it's the key (like looping over a dictionary in most languages)
+**/
+type Iterator interface {
+	Next() (key Object, value Object, ok bool)
+}
+
+type Iterable interface {
+	Iter() Iterator
+}
+
+// Arrays: index, element
+type arrayIterator struct {
+	array *Array
+	index int
+}
+
+func (a *Array) Iter() Iterator { return &arrayIterator{array: a} }
+
+func (it *arrayIterator) Next() (Object, Object, bool) {
+	// the array can shrink while we loop over it (pop, shift), check against its current length
+	if it.index >= len(it.array.Elements) {
+		return nil, nil, false
+	}
+
+	key := &Integer{Value: int64(it.index)}
+	value := it.array.Elements[it.index]
+	it.index++
+
+	return key, value, true
+}
+
+// Hashes: key, value in insertion order
+type hashIterator struct {
+	hash *Hash
+	keys []HashKey
+	idx  int
+}
+
+func (h *Hash) Iter() Iterator {
+	// take a snapshot of the keys, adding / removing keys inside of the loop doesn't affect it
+	keys := make([]HashKey, len(h.keys))
+	copy(keys, h.keys)
+
+	return &hashIterator{hash: h, keys: keys}
+}
+
+func (it *hashIterator) Next() (Object, Object, bool) {
+	for it.idx < len(it.keys) {
+		pair, ok := it.hash.Get(it.keys[it.idx])
+		it.idx++
+
+		// the key was deleted while looping
+		if !ok {
+			continue
+		}
+
+		return pair.Key, pair.Value, true
+	}
+
+	return nil, nil, false
+}
+
+// Strings: index, character (not bytes, see object.String)
+type stringIterator struct {
+	chars []rune
+	index int
+}
+
+func (s *String) Iter() Iterator { return &stringIterator{chars: []rune(s.Value)} }
+
+func (it *stringIterator) Next() (Object, Object, bool) {
+	if it.index >= len(it.chars) {
+		return nil, nil, false
+	}
+
+	key := &Integer{Value: int64(it.index)}
+	value := &String{Value: string(it.chars[it.index])}
+	it.index++
+
+	return key, value, true
+}
+
+/**
+A range of integers from Start (included) to End (excluded), Step at a time: range(0, 10, 2) => 0, 2, 4, 6, 8
+The numbers are generated while looping, so a range doesn't take up any memory no matter how big it is.
+**/
+type Range struct {
+	Start int64
+	End   int64
+	Step  int64
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+func (r *Range) Inspect() string {
+	if r.Step == 1 {
+		return fmt.Sprintf("range(%d, %d)", r.Start, r.End)
+	}
+
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.End, r.Step)
+}
+
+// Ranges: index, number
+type rangeIterator struct {
+	r       *Range
+	current int64
+	index   int64
+	done    bool // the next number would be past the end of int64
+}
+
+func (r *Range) Iter() Iterator { return &rangeIterator{r: r, current: r.Start} }
+
+func (it *rangeIterator) Next() (Object, Object, bool) {
+	if it.done || (it.r.Step > 0 && it.current >= it.r.End) || (it.r.Step < 0 && it.current <= it.r.End) || it.r.Step == 0 {
+		return nil, nil, false
+	}
+
+	key := &Integer{Value: it.index}
+	value := &Integer{Value: it.current}
+	it.index++
+
+	// adding the step would wrap around to the other end of int64 and start over, stop instead
+	if (it.r.Step > 0 && it.current > math.MaxInt64-it.r.Step) || (it.r.Step < 0 && it.current < math.MinInt64-it.r.Step) {
+		it.done = true
+	} else {
+		it.current += it.r.Step
+	}
+
+	return key, value, true
+}
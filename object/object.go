@@ -2,9 +2,12 @@ package object
 
 import (
 	"boar/ast"
+	"boar/token"
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"strconv"
 	"strings"
 )
 
@@ -12,18 +15,23 @@ type ObjectType string
 
 const (
 	INTEGER_OBJ      = "INTEGER"
+	FLOAT_OBJ        = "FLOAT"
 	BOOLEAN_OBJ      = "BOOLEAN"
 	NULL_OBJ         = "NULL"
 	RETURN_VALUE_OBJ = "RETURN VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 	ERROR_OBJ        = "ERROR"
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
+	RANGE_OBJ        = "RANGE"
 )
 
-type BuiltinFunction func(args ...Object) Object
+// ctx is the context of the program calling the builtin: where puts() writes to, where gets() reads from, etc
+type BuiltinFunction func(ctx *Context, args ...Object) Object
 
 type Builtin struct {
 	Fn BuiltinFunction
@@ -49,6 +57,34 @@ func (i *Integer) Type() ObjectType {
 	return INTEGER_OBJ
 }
 
+type Float struct {
+	Value float64
+}
+
+/**
+Floats always print with a decimal point (2.0, not 2) so they can be told apart from integers.
+Really big / small values use exponent notation: 1e+21, 1e-07
+**/
+func (f *Float) Inspect() string {
+	abs := math.Abs(f.Value)
+
+	if abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		return strconv.FormatFloat(f.Value, 'g', -1, 64)
+	}
+
+	s := strconv.FormatFloat(f.Value, 'f', -1, 64)
+
+	if !strings.ContainsAny(s, ".IN") {
+		s += ".0"
+	}
+
+	return s
+}
+
+func (f *Float) Type() ObjectType {
+	return FLOAT_OBJ
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -78,29 +114,134 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+/**
+Loop control flow signals, produced by break / continue statements.
+Like return values they stop the evaluation of the block they're in and bubble up to the closest loop.
+**/
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
+// Kinds of errors, a catch block can tell them apart through e.kind
+const (
+	RUNTIME_ERROR       = "RuntimeError"      // anything that doesn't have a more specific kind
+	TYPE_ERROR          = "TypeError"         // unsupported operator or operand types: 1 + true, -"a", 5()
+	NAME_ERROR          = "NameError"         // unknown identifier
+	ARGUMENT_ERROR      = "ArgumentError"     // wrong number of arguments, unknown named argument
+	ZERO_DIVISION_ERROR = "ZeroDivisionError" // 1 / 0, 1 % 0
+	INDEX_ERROR         = "IndexError"        // index assignment out of range, pop() on an empty array
+	MATCH_ERROR         = "MatchError"        // no arm of a match expression matches the value
+	RECURSION_ERROR     = "RecursionError"    // too many nested function calls
+	STEP_LIMIT_ERROR    = "StepLimitError"    // the program ran more steps than its context allows, see Context.MaxSteps
+	INTERNAL_ERROR      = "InternalError"     // a bug in the interpreter itself, see evalProgram
+	THROWN_ERROR        = "Error"             // throw "something"
+)
+
 type Error struct {
-	Message string
+	Message  string
+	Kind     string         // one of the *_ERROR kinds above (or a custom one from a thrown hash), empty means RUNTIME_ERROR
+	Value    Object         // the value passed to throw, nil for runtime errors
+	Pos      token.Position // where in the source code the error happened
+	Function string         // name of the function the error happened in, empty at the top level or in anonymous functions
+	Trace    []StackFrame   // the calls the error went through, innermost first
+}
+
+// Max number of frames StackTrace() prints, deep recursion would print thousands of them otherwise
+const MAX_PRINTED_FRAMES = 20
+
+/**
+One function call of a stack trace:
+	at fact(0) (3:12)
+
+- Function is the name of the called function (or "anonymous function")
+- Pos is where the call happened
+- Args is a short summary of the arguments it was called with
+**/
+type StackFrame struct {
+	Function string
+	Pos      token.Position
+	Args     string
+}
+
+func (sf StackFrame) String() string {
+	return "at " + sf.Function + "(" + sf.Args + ") (" + sf.Pos.String() + ")"
+}
+
+// The error's kind, RUNTIME_ERROR if none was set
+func (e *Error) KindName() string {
+	if e.Kind == "" {
+		return RUNTIME_ERROR
+	}
+	return e.Kind
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	msg := "ERROR: " + e.Message
+
+	if e.Pos.IsValid() {
+		msg = "ERROR: " + e.Pos.String() + ": " + e.Message
+	}
+
+	if e.Function != "" {
+		msg += " (in fn " + e.Function + ")"
+	}
+
+	return msg
+}
+
+/**
+The error followed by the calls it went through:
+
+ERROR: 2:7: identifier not found: missing (in fn inner)
+  at inner(1, "a") (5:8)
+  at outer() (7:1)
+**/
+func (e *Error) StackTrace() string {
+	var out bytes.Buffer
+
+	out.WriteString(e.Inspect())
+
+	for i, frame := range e.Trace {
+		if i == MAX_PRINTED_FRAMES {
+			out.WriteString(fmt.Sprintf("\n  ... %d more", len(e.Trace)-MAX_PRINTED_FRAMES))
+			break
+		}
+
+		out.WriteString("\n  ")
+		out.WriteString(frame.String())
+	}
+
+	return out.String()
+}
 
 type Function struct {
+	Name       string // empty for anonymous functions: let f = fn(x) { ... }
 	Parameters []*ast.Identifier
+	Defaults   map[string]ast.Expression // default values of the optional parameters
+	Rest       *ast.Identifier           // collects the extra arguments, nil if there isn't one
 	Body       *ast.BlockStatement
 	Env        *Environment //the function scope
+	Locals     int          // slots a call needs for its local variables, see resolver
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
 func (f *Function) Inspect() string {
 	var out bytes.Buffer
 
-	params := []string{}
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
-	}
+	params := ast.ParameterStrings(f.Parameters, f.Defaults, f.Rest)
 
 	out.WriteString("fn")
+	if f.Name != "" {
+		out.WriteString(" ")
+		out.WriteString(f.Name)
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
@@ -111,6 +252,24 @@ func (f *Function) Inspect() string {
 
 }
 
+// The function's name for error messages
+func (f *Function) DisplayName() string {
+	if f.Name == "" {
+		return "anonymous function"
+	}
+	return f.Name
+}
+
+// Reports whether the function has a (non-rest) parameter with the given name
+func (f *Function) HasParameter(name string) bool {
+	for _, p := range f.Parameters {
+		if p.Value == name {
+			return true
+		}
+	}
+	return false
+}
+
 type String struct {
 	Value string
 }
@@ -120,15 +279,45 @@ func (s *String) Inspect() string  { return s.Value }
 
 type Array struct {
 	Elements []Object
+	Frozen   bool // set by freeze(), changing the array is a TypeError
 }
 
 func (a *Array) Type() ObjectType { return ARRAY_OBJ }
-func (a *Array) Inspect() string {
+func (a *Array) Inspect() string  { return inspect(a, map[Object]bool{}) }
+
+/**
+Arrays and hashes can contain themselves (a[0] = a), so the ones being printed are kept in printing
+and one that shows up inside of itself is printed as [...] / {...} instead of recursing until the Go stack runs out
+**/
+func inspect(obj Object, printing map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Array:
+		if printing[obj] {
+			return "[...]"
+		}
+		printing[obj] = true
+		defer delete(printing, obj)
+
+		return obj.inspect(printing)
+	case *Hash:
+		if printing[obj] {
+			return "{...}"
+		}
+		printing[obj] = true
+		defer delete(printing, obj)
+
+		return obj.inspect(printing)
+	}
+
+	return obj.Inspect()
+}
+
+func (a *Array) inspect(printing map[Object]bool) string {
 	var out bytes.Buffer
 
 	elements := []string{}
 	for _, e := range a.Elements {
-		elements = append(elements, e.Inspect())
+		elements = append(elements, inspect(e, printing))
 	}
 
 	out.WriteString("[")
@@ -159,6 +348,18 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+/**
+Whole floats hash the same way as the equivalent integer, since 1 == 1.0 both {1: "a"}[1.0] and {1.0: "a"}[1] work.
+Every other float hashes its bits.
+**/
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+		return HashKey{Type: INTEGER_OBJ, Value: uint64(int64(f.Value))}
+	}
+
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -171,18 +372,76 @@ type HashPair struct {
 	Value Object
 }
 
+/**
+Hashes keep their keys in the order they were added: printing, looping and keys() / values() / entries()
+all go through them in that order. Setting an existing key keeps its place, deleting a key removes it.
+
+The zero value is an empty hash, use Get / Set / Delete instead of touching the fields.
+**/
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs  map[HashKey]HashPair
+	keys   []HashKey // insertion order
+	Frozen bool      // set by freeze(), changing the hash is a TypeError
+}
+
+func (h *Hash) Get(key HashKey) (HashPair, bool) {
+	pair, ok := h.pairs[key]
+	return pair, ok
+}
+
+// Adds the pair at the end, or replaces the value in place if the key already exists
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if h.pairs == nil {
+		h.pairs = make(map[HashKey]HashPair)
+	}
+
+	if _, exists := h.pairs[key]; !exists {
+		h.keys = append(h.keys, key)
+	}
+
+	h.pairs[key] = pair
+}
+
+// Removes the key, false if it wasn't there. Takes time proportional to the number of keys
+func (h *Hash) Delete(key HashKey) bool {
+	if _, exists := h.pairs[key]; !exists {
+		return false
+	}
+
+	delete(h.pairs, key)
+
+	for idx, k := range h.keys {
+		if k == key {
+			h.keys = append(h.keys[:idx], h.keys[idx+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+func (h *Hash) Len() int { return len(h.keys) }
+
+// The key / value pairs in insertion order, changing the slice doesn't change the hash
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, len(h.keys))
+	for idx, key := range h.keys {
+		pairs[idx] = h.pairs[key]
+	}
+
+	return pairs
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
 
-func (h *Hash) Inspect() string {
+func (h *Hash) Inspect() string { return inspect(h, map[Object]bool{}) }
+
+func (h *Hash) inspect(printing map[Object]bool) string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf(`"%s" : "%s"`, pair.Key.Inspect(), pair.Value.Inspect()))
+	for _, pair := range h.Pairs() {
+		pairs = append(pairs, fmt.Sprintf(`"%s" : "%s"`, inspect(pair.Key, printing), inspect(pair.Value, printing)))
 	}
 
 	out.WriteString("{")
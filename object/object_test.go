@@ -1,6 +1,12 @@
 package object
 
-import "testing"
+import (
+	"boar/token"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -17,3 +23,299 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestFloatHashKey(t *testing.T) {
+	one := &Float{Value: 1.5}
+	same := &Float{Value: 1.5}
+	other := &Float{Value: 2.5}
+
+	if one.HashKey() != same.HashKey() {
+		t.Errorf("floats with same value have different hash keys")
+	}
+	if one.HashKey() == other.HashKey() {
+		t.Errorf("floats with different values have same hash keys")
+	}
+
+	// whole floats and integers are interchangeable as keys
+	if (&Float{Value: 2.0}).HashKey() != (&Integer{Value: 2}).HashKey() {
+		t.Errorf("2.0 and 2 have different hash keys")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1.5, "1.5"},
+		{2, "2.0"},
+		{-0.25, "-0.25"},
+		{1e21, "1e+21"},
+		{1e-7, "1e-07"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect() for %v, expected %q, got %q", tt.value, tt.expected, f.Inspect())
+		}
+	}
+}
+
+func TestIterators(t *testing.T) {
+	tests := []struct {
+		iterable       Iterable
+		expectedKeys   []string
+		expectedValues []string
+	}{
+		{&Array{Elements: []Object{&String{Value: "a"}, &Integer{Value: 2}}}, []string{"0", "1"}, []string{"a", "2"}},
+		{&Array{}, []string{}, []string{}},
+		{&String{Value: "héy"}, []string{"0", "1", "2"}, []string{"h", "é", "y"}},
+		{&Range{Start: 0, End: 3, Step: 1}, []string{"0", "1", "2"}, []string{"0", "1", "2"}},
+		{&Range{Start: 10, End: 0, Step: -4}, []string{"0", "1", "2"}, []string{"10", "6", "2"}},
+		{&Range{Start: 5, End: 0, Step: 1}, []string{}, []string{}},
+		// near the bounds of int64 the range stops instead of wrapping around
+		{&Range{Start: math.MaxInt64 - 7, End: math.MaxInt64, Step: 5}, []string{"0", "1"}, []string{"9223372036854775800", "9223372036854775805"}},
+		{&Range{Start: math.MaxInt64 - 2, End: math.MaxInt64, Step: math.MaxInt64}, []string{"0"}, []string{"9223372036854775805"}},
+		{&Range{Start: math.MinInt64 + 7, End: math.MinInt64, Step: -5}, []string{"0", "1"}, []string{"-9223372036854775801", "-9223372036854775806"}},
+		{&Range{Start: 0, End: math.MinInt64, Step: math.MinInt64}, []string{"0"}, []string{"0"}},
+	}
+
+	for _, tt := range tests {
+		keys := []string{}
+		values := []string{}
+
+		iterator := tt.iterable.Iter()
+		for key, value, ok := iterator.Next(); ok; key, value, ok = iterator.Next() {
+			keys = append(keys, key.Inspect())
+			values = append(values, value.Inspect())
+		}
+
+		if strings.Join(keys, ",") != strings.Join(tt.expectedKeys, ",") {
+			t.Errorf("wrong keys, expected %v, got %v", tt.expectedKeys, keys)
+		}
+
+		if strings.Join(values, ",") != strings.Join(tt.expectedValues, ",") {
+			t.Errorf("wrong values, expected %v, got %v", tt.expectedValues, values)
+		}
+	}
+}
+
+func TestHashIterator(t *testing.T) {
+	one, two, three := &String{Value: "one"}, &String{Value: "two"}, &String{Value: "three"}
+	hash := &Hash{}
+	hash.Set(one.HashKey(), HashPair{Key: one, Value: &Integer{Value: 1}})
+	hash.Set(two.HashKey(), HashPair{Key: two, Value: &Integer{Value: 2}})
+
+	seen := []string{}
+
+	iterator := hash.Iter()
+	for key, value, ok := iterator.Next(); ok; key, value, ok = iterator.Next() {
+		seen = append(seen, key.Inspect()+"="+value.Inspect())
+		// deleting while iterating doesn't break the iterator, keys added while iterating aren't seen
+		hash.Delete(two.HashKey())
+		hash.Set(three.HashKey(), HashPair{Key: three, Value: &Integer{Value: 3}})
+	}
+
+	if strings.Join(seen, ",") != "one=1" {
+		t.Errorf("wrong pairs, got %v", seen)
+	}
+}
+
+func TestHashOrder(t *testing.T) {
+	hash := &Hash{}
+	keys := []string{"b", "a", "c", "d"}
+
+	for idx, key := range keys {
+		str := &String{Value: key}
+		hash.Set(str.HashKey(), HashPair{Key: str, Value: &Integer{Value: int64(idx)}})
+	}
+
+	a, b, d := &String{Value: "a"}, &String{Value: "b"}, &String{Value: "d"}
+	// setting an existing key keeps its place
+	hash.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 10}})
+
+	if !hash.Delete(b.HashKey()) || hash.Delete(b.HashKey()) {
+		t.Errorf("expected the first delete of b to succeed and the second one to fail")
+	}
+	// deleted keys go at the end when they come back
+	hash.Delete(d.HashKey())
+	hash.Set(b.HashKey(), HashPair{Key: b, Value: &Integer{Value: 20}})
+
+	if hash.Len() != 3 {
+		t.Errorf("wrong length, expected 3, got %d", hash.Len())
+	}
+
+	if _, ok := hash.Get(d.HashKey()); ok {
+		t.Errorf("expected d to be deleted")
+	}
+
+	expected := `{"a" : "10", "c" : "2", "b" : "20"}`
+	if hash.Inspect() != expected {
+		t.Errorf("wrong order, expected %s, got %s", expected, hash.Inspect())
+	}
+}
+
+func TestErrorStackTrace(t *testing.T) {
+	err := &Error{
+		Message:  "identifier not found: x",
+		Pos:      token.Position{File: "main.br", Line: 2, Column: 5},
+		Function: "inner",
+		Trace: []StackFrame{
+			{Function: "inner", Pos: token.Position{File: "main.br", Line: 5, Column: 6}, Args: `1, "a"`},
+			{Function: "outer", Pos: token.Position{File: "main.br", Line: 7, Column: 6}},
+		},
+	}
+
+	expected := `ERROR: main.br:2:5: identifier not found: x (in fn inner)
+  at inner(1, "a") (main.br:5:6)
+  at outer() (main.br:7:6)`
+
+	if err.StackTrace() != expected {
+		t.Errorf("wrong stack trace, expected:\n%s\ngot:\n%s", expected, err.StackTrace())
+	}
+
+	// without frames it's the same as Inspect()
+	err.Trace = nil
+	if err.StackTrace() != err.Inspect() {
+		t.Errorf("wrong stack trace without frames, expected %q, got %q", err.Inspect(), err.StackTrace())
+	}
+}
+
+func TestLongStackTrace(t *testing.T) {
+	err := &Error{Message: "boom"}
+
+	for i := 0; i < MAX_PRINTED_FRAMES+5; i++ {
+		err.Trace = append(err.Trace, StackFrame{Function: "rec", Pos: token.Position{Line: 1, Column: 1}, Args: "1"})
+	}
+
+	lines := strings.Split(err.StackTrace(), "\n")
+
+	// the error, the printed frames and the "... N more" line
+	if len(lines) != MAX_PRINTED_FRAMES+2 {
+		t.Fatalf("wrong number of lines, expected %d, got %d", MAX_PRINTED_FRAMES+2, len(lines))
+	}
+
+	if lines[len(lines)-1] != "  ... 5 more" {
+		t.Errorf("wrong last line, got %q", lines[len(lines)-1])
+	}
+}
+
+func TestEnvironmentContext(t *testing.T) {
+	global := NewEnvironment()
+	inner := NewEnclosedEnvironment(NewEnclosedEnvironment(global))
+
+	if inner.Context() != DefaultContext() {
+		t.Fatalf("expected the default context before SetContext is called")
+	}
+
+	ctx := NewContext(strings.NewReader(""), io.Discard, io.Discard)
+	inner.SetContext(ctx)
+
+	// every scope of the program shares the same context
+	if global.Context() != ctx || inner.Context() != ctx {
+		t.Fatalf("expected every scope to use the context that was set")
+	}
+}
+
+func TestEnvironmentSlots(t *testing.T) {
+	global := NewEnvironment()
+	global.Set("g", &Integer{Value: 1})
+
+	outer := NewScope(global, 2)
+	inner := NewScope(outer, 1)
+
+	if _, ok := inner.GetAt(1, 0); ok {
+		t.Fatalf("expected a slot without a value to not be found")
+	}
+
+	inner.SetAt(1, 0, &Integer{Value: 2})
+	inner.SetAt(0, 0, &Integer{Value: 3})
+
+	if val, ok := outer.GetAt(0, 0); !ok || val.(*Integer).Value != 2 {
+		t.Errorf("expected the outer scope to have 2 in its first slot, got %v", val)
+	}
+	if val, ok := inner.GetAt(0, 0); !ok || val.(*Integer).Value != 3 {
+		t.Errorf("expected the inner scope to have 3 in its first slot, got %v", val)
+	}
+
+	// globals are found from any scope
+	inner.SetGlobal("h", &Integer{Value: 4})
+
+	if val, ok := inner.GetGlobal("g"); !ok || val.(*Integer).Value != 1 {
+		t.Errorf("expected g to be 1, got %v", val)
+	}
+	if !global.HasGlobal("h") {
+		t.Errorf("expected h to be set in the global environment")
+	}
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	global := NewEnvironment()
+	global.Set("x", &Integer{Value: 1})
+
+	inner := NewEnclosedEnvironment(NewEnclosedEnvironment(global))
+	inner.Set("y", &Integer{Value: 2})
+
+	if _, ok := inner.Assign("x", &Integer{Value: 10}); !ok {
+		t.Fatalf("expected x to be assigned")
+	}
+
+	// the owning scope got the value, the inner one didn't get its own x
+	if val, _ := global.Get("x"); val.(*Integer).Value != 10 {
+		t.Errorf("expected x to be 10 in the global scope, got %v", val)
+	}
+	if _, ok := inner.store["x"]; ok {
+		t.Errorf("expected x to not be set in the inner scope")
+	}
+
+	if _, ok := inner.Assign("missing", &Integer{Value: 1}); ok {
+		t.Errorf("expected assigning an undefined variable to fail")
+	}
+	if _, ok := global.Get("missing"); ok {
+		t.Errorf("expected a failed assignment to not define the variable")
+	}
+}
+
+func TestEnvironmentConstants(t *testing.T) {
+	global := NewEnvironment()
+	global.SetConst("limit", &Integer{Value: 1})
+
+	inner := NewScope(global, 1)
+	inner.SetConstAt(0, 0, "local", &Integer{Value: 2})
+
+	tests := []struct {
+		name   string
+		result Object
+	}{
+		{"limit", global.Set("limit", &Integer{Value: 5})},
+		{"limit", global.SetConst("limit", &Integer{Value: 5})},
+		{"limit", inner.SetGlobal("limit", &Integer{Value: 5})},
+		{"local", inner.SetAt(0, 0, &Integer{Value: 5})},
+	}
+
+	for _, tt := range tests {
+		err, ok := tt.result.(*Error)
+		if !ok || err.Kind != TYPE_ERROR || err.Message != "cannot assign to constant "+tt.name {
+			t.Errorf("expected a TypeError for %s, got %+v", tt.name, tt.result)
+		}
+	}
+
+	if result, found := NewEnclosedEnvironment(global).Assign("limit", &Integer{Value: 5}); !found || !isError(result) {
+		t.Errorf("expected assigning to a constant to fail, got %+v", result)
+	}
+
+	if val, _ := global.Get("limit"); val.(*Integer).Value != 1 {
+		t.Errorf("expected limit to still be 1, got %s", val.Inspect())
+	}
+
+	// an inner scope can have its own variable with the same name
+	if result := NewEnclosedEnvironment(global).Set("limit", &Integer{Value: 5}); isError(result) {
+		t.Errorf("expected shadowing a constant to work, got %+v", result)
+	}
+}
+
+func isError(obj Object) bool {
+	_, ok := obj.(*Error)
+	return ok
+}
@@ -2,9 +2,9 @@ package parser
 
 import (
 	"boar/ast"
+	"boar/diagnostic"
 	"boar/lexer"
 	"boar/token"
-	"fmt"
 	"strconv"
 )
 
@@ -26,11 +26,14 @@ TLDR:
 const (
 	_ int = iota
 	LOWEST
+	OR            // ||
+	AND           // &&
 	EQUALS        // ==
 	LESSGREATER   // < or >
 	SUM           // +
 	PRODUCT       // *
 	PREFIX        // -X or !X
+	POWER         // ** (binds tighter than prefixes: -2 ** 2 == -(2 ** 2))
 	ASSIGN        // =
 	CALL          // myFunction(x)
 	INDEX         //array[index]
@@ -45,20 +48,29 @@ ex:
 - these tokens have a lower precedence than token.ASTERISK and token.SLASH
 **/
 var precedences = map[token.TokenType]int{
+	token.OR:       OR,
+	token.AND:      AND,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.LT_EQ:    LESSGREATER,
+	token.GT_EQ:    LESSGREATER,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
+	token.PERCENT:  PRODUCT,
+	token.POWER:    POWER,
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
 	token.DOT:      INTERNAL_CALL,
 	token.ASSIGN:   ASSIGN,
 }
 
+// How deep expressions and blocks can be nested, see enterNesting
+const MAX_NESTING_DEPTH = 1000
+
 /**
 Prefix and infix parsing functions
 
@@ -79,8 +91,23 @@ type Parser struct {
 	// token values
 	curToken  token.Token
 	peekToken token.Token
-	// slice of error strings
-	errors []string
+	// tokens after peekToken that were already read from the lexer (see peekAhead), nextToken uses them up first
+	lookahead []token.Token
+	// errors (and other diagnostics) found while parsing
+	diagnostics []*diagnostic.Diagnostic
+	// number of braces '{' and parentheses / brackets '(' '[' opened (and not closed yet) up to and including curToken
+	// used to resynchronize at the right nesting level after a syntax error
+	braceDepth int
+	groupDepth int
+	// number of loops the current token is in (reset inside of function bodies), break / continue need at least one
+	loopDepth int
+	// how deeply nested (expressions and blocks) the current token is, see enterNesting
+	nestingDepth int
+	// whether the statement starting at the { at a given offset is a block, see isBlockStart
+	blockStarts map[int]bool
+	// while isBlockStart looks ahead (recording > 0), the tokens read from the lexer are kept in recorded
+	recording int
+	recorded  []token.Token
 
 	//parsing functions
 	/**
@@ -96,7 +123,7 @@ type Parser struct {
 
 func New(l *lexer.Lexer) *Parser {
 	// generate a pointer to this new Parser struct
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l, diagnostics: []*diagnostic.Diagnostic{}, blockStarts: map[int]bool{}}
 
 	// Read two tokens, so curToken and peekToken are both set
 	p.nextToken()
@@ -109,6 +136,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	// If we encounter a token of type token.INT, call parseIntegerLiteral
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	// If we encounter a token of type BANG (!), call this function
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
@@ -118,11 +146,16 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	// if expressions
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	// match expressions
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 	// function expressions
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.INTERP_START, p.parseInterpolatedString)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	// illegal characters, unterminated comments, etc
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 
 	// Initialize the infix parse function map
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -134,6 +167,12 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.POWER, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseLogicalExpression)
+	p.registerInfix(token.OR, p.parseLogicalExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
 	p.registerInfix(token.DOT, p.parseInternalCallExpression)
@@ -157,8 +196,49 @@ func (p *Parser) parseIdentifier() ast.Expression {
 // Helper method to advance token pointers
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
-	// parser.lexer.nextToken
-	p.peekToken = p.l.NextToken()
+
+	if len(p.lookahead) > 0 {
+		p.peekToken = p.lookahead[0]
+		p.lookahead = p.lookahead[1:]
+	} else {
+		p.peekToken = p.readToken()
+	}
+
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.braceDepth++
+	case token.RBRACE:
+		p.braceDepth--
+	case token.LPAREN, token.LBRACKET:
+		p.groupDepth++
+	case token.RPAREN, token.RBRACKET:
+		p.groupDepth--
+	}
+}
+
+// parser.lexer.nextToken
+func (p *Parser) readToken() token.Token {
+	tok := p.l.NextToken()
+
+	// comments don't mean anything to the parser, skip them if the lexer is emitting them
+	for tok.Type == token.COMMENT {
+		tok = p.l.NextToken()
+	}
+
+	if p.recording > 0 {
+		p.recorded = append(p.recorded, tok)
+	}
+
+	return tok
+}
+
+// The token n positions after peekToken (peekAhead(0) is the one right after it), without moving on
+func (p *Parser) peekAhead(n int) token.Token {
+	for len(p.lookahead) <= n {
+		p.lookahead = append(p.lookahead, p.readToken())
+	}
+
+	return p.lookahead[n]
 }
 
 func (p *Parser) ParseProgram() *ast.Program {
@@ -168,8 +248,8 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 	// Loop until we reach a null token / no token
 	for !p.curTokenIs(token.EOF) {
-		// parse the current statement
-		stmt := p.parseStatement()
+		// parse the current statement, skipping ahead to the next one if it's invalid
+		stmt := p.parseStatementWithRecovery()
 
 		if stmt != nil {
 			// add the current statement to the program statements slice
@@ -183,22 +263,77 @@ func (p *Parser) ParseProgram() *ast.Program {
 }
 
 func (p *Parser) parseStatement() ast.Statement {
+	/**
+		note: the parsing functions return a nil pointer when the statement is invalid,
+		make sure we hand back a real nil ast.Statement in that case (not an interface wrapping a nil pointer)
+	**/
+
+	// fn name(...) { ... } is a declaration, fn(...) { ... } is a function literal expression
+	if p.curTokenIs(token.FUNCTION) && p.peekTokenIs(token.IDENT) {
+		if stmt := p.parseFunctionStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
+	}
+
 	switch p.curToken.Type {
-	case token.LET:
-		return p.parseLetStatement()
+	case token.LET, token.CONST:
+		if stmt := p.parseLetStatement(); stmt != nil {
+			return stmt
+		}
 	case token.RETURN:
-		return p.parseReturnStatement()
+		if stmt := p.parseReturnStatement(); stmt != nil {
+			return stmt
+		}
 	case token.FOR:
-		return p.parseForLoopStatement()
+		if stmt := p.parseForStatement(); stmt != nil {
+			return stmt
+		}
+	case token.WHILE:
+		if stmt := p.parseWhileStatement(); stmt != nil {
+			return stmt
+		}
+	case token.BREAK:
+		if stmt := p.parseBreakStatement(); stmt != nil {
+			return stmt
+		}
+	case token.CONTINUE:
+		if stmt := p.parseContinueStatement(); stmt != nil {
+			return stmt
+		}
+	case token.TRY:
+		if stmt := p.parseTryStatement(); stmt != nil {
+			return stmt
+		}
+	case token.THROW:
+		if stmt := p.parseThrowStatement(); stmt != nil {
+			return stmt
+		}
+	// { ... } on its own is a block with its own scope, unless it's a hash literal
+	case token.LBRACE:
+		if p.isBlockStart() {
+			block := p.parseBlockStatement()
+			if p.peekTokenIs(token.SEMICOLON) {
+				p.nextToken()
+			}
+			return block
+		}
+		if stmt := p.parseExpressionStatement(); stmt != nil {
+			return stmt
+		}
 	default:
 		// by default we'll parse it as an expression: x, foobar, x + y, etc
-		return p.parseExpressionStatement()
+		if stmt := p.parseExpressionStatement(); stmt != nil {
+			return stmt
+		}
 	}
+
+	return nil
 }
 
 func (p *Parser) parseLetStatement() *ast.LetStatement {
-	// grabs the 'let' statement
-	stmt := &ast.LetStatement{Token: p.curToken}
+	// grabs the 'let' statement, const x = 5 is a let statement that can't be reassigned
+	stmt := &ast.LetStatement{Token: p.curToken, Const: p.curTokenIs(token.CONST)}
 	// We expect to find an identifier: let x, let a, let etc
 	if !p.expectPeek(token.IDENT) {
 		return nil
@@ -217,6 +352,10 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
+	if stmt.Value == nil {
+		return nil
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -231,6 +370,10 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
+	if stmt.ReturnValue == nil {
+		return nil
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -240,11 +383,34 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 // Create an error when no prefix parse function has been found
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addDiagnostic(diagnostic.NewError(diagnostic.NO_PREFIX_PARSE_FN, p.curToken, "no prefix parse function for %s found", t))
+}
+
+/**
+Reports an illegal token.
+If the lexer already knows what went wrong (ex: an unterminated comment) use its diagnostic,
+otherwise its just a character we don't understand.
+**/
+func (p *Parser) parseIllegal() ast.Expression {
+	if diagnostics := p.l.DiagnosticsFor(p.curToken); len(diagnostics) > 0 {
+		for _, d := range diagnostics {
+			p.addDiagnostic(d)
+		}
+		return nil
+	}
+
+	p.addDiagnostic(diagnostic.NewError(diagnostic.ILLEGAL_CHARACTER, p.curToken, "illegal character %q", p.curToken.Literal))
+	return nil
 }
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	depth := p.nestingDepth
+	defer func() { p.nestingDepth = depth }()
+
+	if !p.enterNesting() {
+		return nil
+	}
+
 	// See if the current token is registered to a parsing function
 	prefix := p.prefixParseFns[p.curToken.Type]
 
@@ -259,6 +425,11 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	**/
 	leftExp := prefix()
 
+	// the prefix parsing function already reported why it failed
+	if leftExp == nil {
+		return nil
+	}
+
 	/*
 		- find the infix parsing function for the next token (if it exists)
 		- If it exists, call it, building up the Infix Expression Node
@@ -289,11 +460,112 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 			-> ( (1+2) + 3) being the final returned expression, where (1+2) is an infix expresssion
 			assigned to the 'left' value of the outer infix expression ((inner) + 3)
 		*/
+		if !p.enterNesting() {
+			return nil
+		}
 		leftExp = infix(leftExp)
+
+		if leftExp == nil {
+			return nil
+		}
 	}
 	return leftExp
 }
 
+/**
+Goes one level deeper into the code being parsed: ((((1)))), -(-(-1)), 1 + 1 + 1, { { } }, etc
+
+Parsing and evaluating nested code takes up Go stack space for every level,
+so we refuse anything nested deeper than MAX_NESTING_DEPTH instead of letting
+something like 100.000 opening parentheses blow up the stack and crash the process.
+The caller restores nestingDepth once it's done with its level.
+**/
+func (p *Parser) enterNesting() bool {
+	p.nestingDepth++
+
+	if p.nestingDepth > MAX_NESTING_DEPTH {
+		p.addDiagnostic(diagnostic.NewError(diagnostic.TOO_DEEPLY_NESTED, p.curToken, "code nested too deeply, more than %d levels", MAX_NESTING_DEPTH))
+		return false
+	}
+
+	return true
+}
+
+/**
+Tells a block from a hash literal when a statement starts with {, by parsing the first expression inside of the braces
+and looking at the token right after it:
+- a : means the expression was the first key of a hash: {"a": 1}, {key: value}, {"a" + "b": 1}
+- anything else (a ;, another expression, the closing }) or no expression at all (a let) means it's a block:
+  { let x = 1; x }, { 1 }, { "a" }, { x; y: 1 } (a block with a syntax error in it)
+- {} is an empty hash
+
+The expression is only parsed to look at what follows it: its errors are dropped and its tokens go back in front of
+peekToken, so the statement then gets parsed for real.
+Blocks inside of that expression (function bodies) are skipped without parsing them while looking ahead,
+and blockStarts keeps the answers so a { is only looked ahead from once.
+**/
+func (p *Parser) isBlockStart() bool {
+	if p.peekTokenIs(token.RBRACE) {
+		return false
+	}
+
+	offset := p.curToken.Pos.Offset
+	if isBlock, ok := p.blockStarts[offset]; ok {
+		return isBlock
+	}
+
+	saved := *p
+	firstRecorded := len(p.recorded)
+	p.recording++
+
+	p.nextToken()
+
+	var isBlock bool
+	// { { ... } }: a hash key can't be a block, so when the inner one is a block this one is too,
+	// no need to parse the inner one as a hash (nested blocks would be looked ahead from over and over)
+	if p.curTokenIs(token.LBRACE) && p.isBlockStart() {
+		isBlock = true
+	} else {
+		errorsBefore := p.errorCount()
+		p.parseExpression(LOWEST)
+		isBlock = p.errorCount() > errorsBefore || !p.peekTokenIs(token.COLON)
+	}
+
+	// everything read from the lexer while looking ahead comes right after the tokens that were already in lookahead
+	read := p.recorded[firstRecorded:]
+	recorded := p.recorded
+
+	*p = saved
+	// capped so the tokens read don't get written over whatever comes after saved.lookahead in its array
+	p.lookahead = append(saved.lookahead[:len(saved.lookahead):len(saved.lookahead)], read...)
+	if p.recording > 0 {
+		// an outer isBlockStart is looking ahead too, it needs every token it read
+		p.recorded = recorded
+	} else {
+		p.recorded = nil
+	}
+
+	p.blockStarts[offset] = isBlock
+
+	return isBlock
+}
+
+// Moves from the { of a block to its matching } (or EOF) without parsing what's in between
+func (p *Parser) skipBlock() {
+	depth := 1
+
+	for depth > 0 && !p.peekTokenIs(token.EOF) {
+		p.nextToken()
+
+		switch p.curToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			depth--
+		}
+	}
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	// Create an ExpressionStatement AST Node
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
@@ -302,6 +574,10 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 		we didn't parse anything yet and we can't compare precedences.
 	*/
 	stmt.Expression = p.parseExpression(LOWEST)
+
+	if stmt.Expression == nil {
+		return nil
+	}
 	// If the next token is a semicolon, move onto the next token
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -330,15 +606,37 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
-//Returns any parser errors
+//Returns any parser errors as "line:column: message" strings
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := []string{}
+
+	for _, d := range p.diagnostics {
+		if d.Severity == diagnostic.ERROR {
+			errors = append(errors, d.String())
+		}
+	}
+
+	return errors
+}
+
+// Returns every diagnostic found while parsing, errors included
+func (p *Parser) Diagnostics() []*diagnostic.Diagnostic {
+	return p.diagnostics
+}
+
+func (p *Parser) addDiagnostic(d *diagnostic.Diagnostic) {
+	p.diagnostics = append(p.diagnostics, d)
 }
 
 // Adds any errors we encountered while peeking in expectPeek()
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	d := diagnostic.NewError(diagnostic.UNEXPECTED_TOKEN, p.peekToken, "expected next token to be %s, got %s instead", t, p.peekToken.Type)
+
+	if p.peekTokenIs(token.EOF) {
+		d.WithHint("the input ended early, check for an unfinished expression or a missing %s", t)
+	}
+
+	p.addDiagnostic(d)
 }
 
 /**
@@ -371,8 +669,22 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_INTEGER, p.curToken, "could not parse %q as integer", p.curToken.Literal))
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+	// convert string into a float64
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+
+	if err != nil {
+		p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_FLOAT, p.curToken, "could not parse %q as float", p.curToken.Literal))
 		return nil
 	}
 
@@ -438,11 +750,43 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 		before advancing the token pointers.
 	*/
 	precedence := p.curPrecedence()
+
+	/*
+		** is right associative: 2 ** 3 ** 2 == 2 ** (3 ** 2)
+		lowering the precedence lets the right side grab the next ** before we do.
+	*/
+	if expression.Operator == "**" {
+		precedence--
+	}
+
 	// Point to the next token
 	p.nextToken()
 	// Parse and grab the next AST Node
 	expression.Right = p.parseExpression(precedence)
 
+	if expression.Right == nil {
+		return nil
+	}
+
+	return expression
+}
+
+// Same as parseInfixExpression but for && and ||, which get their own node so they can short-circuit
+func (p *Parser) parseLogicalExpression(left ast.Expression) ast.Expression {
+	expression := &ast.LogicalExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+
+	precedence := p.curPrecedence()
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+
+	if expression.Right == nil {
+		return nil
+	}
+
 	return expression
 }
 
@@ -451,7 +795,7 @@ func (p *Parser) parseGroupedExpression() ast.Expression {
 
 	exp := p.parseExpression(LOWEST)
 
-	if !p.expectPeek(token.RPAREN) {
+	if exp == nil || !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 
@@ -470,6 +814,10 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	p.nextToken()
 	expression.Condition = p.parseExpression(LOWEST)
 
+	if expression.Condition == nil {
+		return nil
+	}
+
 	/**
 		Make sure we encounter a right parenthesis, progress tokens if we do
 		Then make sure we encounter a left brace {, progress tokens if we do
@@ -495,6 +843,25 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekTokenIs(token.ELSE) {
 		// we're currently sisting on the 'else' token, move up the tokens
 		p.nextToken()
+
+		// else if (...) { ... } is an else block that only contains another if expression
+		if p.peekTokenIs(token.IF) {
+			block := &ast.BlockStatement{Token: p.curToken}
+			p.nextToken()
+
+			stmt := &ast.ExpressionStatement{Token: p.curToken}
+			stmt.Expression = p.parseIfExpression()
+
+			if stmt.Expression == nil {
+				return nil
+			}
+
+			block.Statements = []ast.Statement{stmt}
+			expression.Alternative = block
+
+			return expression
+		}
+
 		// If for some reason theres not a LBRACE token immediately after the else the expression is invalid
 		if !p.expectPeek(token.LBRACE) {
 			return nil
@@ -506,134 +873,483 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
-func (p *Parser) parseBlockStatement() *ast.BlockStatement {
-	block := &ast.BlockStatement{Token: p.curToken}
-	block.Statements = []ast.Statement{}
-
-	// Jump over the LBRACE token
-	p.nextToken()
-	// Continue parsing the statement until we reach the end of the block or token.EOF
-	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			block.Statements = append(block.Statements, stmt)
-		}
-		p.nextToken()
-	}
-
-	return block
+/**
+match (subject) {
+	1, 2 => "small",
+	_ if subject > 100 => "big",
+	_ => { "something else" }
 }
 
-func (p *Parser) parseFunctionLiteral() ast.Expression {
-	func_lit := &ast.FunctionLiteral{Token: p.curToken}
+- Arms are separated by commas, the trailing comma is optional.
+- An arm body is either a block or a single expression.
+**/
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
 
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
 
-	func_lit.Parameters = p.parseFunctionParameters()
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
 
-	if !p.expectPeek(token.LBRACE) {
+	if expression.Subject == nil || !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
-	func_lit.Body = p.parseBlockStatement()
+	expression.Arms = []*ast.MatchArm{}
 
-	return func_lit
-}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+		arm := p.parseMatchArm()
 
-	//empty parameter list
-	if p.peekTokenIs(token.RPAREN) {
-		p.nextToken()
-		return identifiers
+		if arm == nil {
+			return nil
+		}
+
+		expression.Arms = append(expression.Arms, arm)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
 	}
 
-	// Move past the parenthesis we're currently on, point to the first identifier
+	// sitting on the closing }
 	p.nextToken()
 
-	// Grab first identifier
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+	return expression
+}
 
-	// Every time we encounter a comma, move token pointers up
-	// so we point to the identifiers
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
-		p.nextToken()
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.curToken}
 
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
-	}
+	pattern := p.parseMatchPattern()
 
-	// no closing parenthesis
-	if !p.expectPeek(token.RPAREN) {
+	if pattern == nil {
 		return nil
 	}
 
-	return identifiers
-}
+	arm.Patterns = []ast.Expression{pattern}
 
-// recieves the already parsed function as argument, uses it to construct call expression node.
-// "leftExp" in parseExpressions gets passed to this infix parsing function
-func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
-	/*
-		p.curToken => left parenthesis
-		function => identifier (i.e.: add, subtract, doTheThing)
-	*/
-	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	// (x,y,z)
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
 
-	return exp
-}
+		pattern := p.parseMatchPattern()
 
-func (p *Parser) parseStringLiteral() ast.Expression {
-	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
-}
+		if pattern == nil {
+			return nil
+		}
 
-// parses a list of expressions until we reach the end of the list (via the end token type)
-func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
-	list := []ast.Expression{}
+		arm.Patterns = append(arm.Patterns, pattern)
+	}
 
-	// empty list
-	if p.peekTokenIs(end) {
+	if p.peekTokenIs(token.IF) {
 		p.nextToken()
-		return list
+		p.nextToken()
+
+		arm.Guard = p.parseExpression(LOWEST)
+
+		if arm.Guard == nil {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
 	}
 
-	// first expression
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
 
-	// For each comma separated expression
-	for p.peekTokenIs(token.COMMA) {
-		// move up 2 tokens (so we land on the expressoin)
-		p.nextToken()
-		p.nextToken()
-		// parse the expression
-		list = append(list, p.parseExpression(LOWEST))
+	if p.curTokenIs(token.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+		return arm
 	}
 
-	// If for some reason we haven't reached the end token we passed, return nil
-	if !p.expectPeek(end) {
+	// a single expression is treated like a block with one statement
+	stmt := &ast.ExpressionStatement{Token: p.curToken}
+	stmt.Expression = p.parseExpression(LOWEST)
+
+	if stmt.Expression == nil {
 		return nil
 	}
 
-	return list
+	arm.Body = &ast.BlockStatement{Token: stmt.Token, Statements: []ast.Statement{stmt}}
 
+	return arm
 }
 
-func (p *Parser) parseArrayLiteral() ast.Expression {
-	// the [ token
-	array := &ast.ArrayLiteral{Token: p.curToken}
-	// Grab all the elements before we reach the right bracket (end of array)
-	array.Elements = p.parseExpressionList(token.RBRACKET)
+// Patterns are literals (1, -1.5, "x", true) or the _ wildcard
+func (p *Parser) parseMatchPattern() ast.Expression {
+	start := p.curToken
+	pattern := p.parseExpression(LOWEST)
 
-	return array
-}
+	if pattern == nil {
+		return nil
+	}
+
+	if !isLiteralPattern(pattern) {
+		p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_PATTERN, start, "invalid match pattern: %s", pattern.String()).
+			WithHint("patterns can be numbers, strings, booleans or _, use a guard for anything else: _ if x > 1 => ..."))
+		return nil
+	}
+
+	return pattern
+}
+
+func isLiteralPattern(pattern ast.Expression) bool {
+	switch pattern := pattern.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	case *ast.PrefixExpression:
+		// negative numbers
+		switch pattern.Right.(type) {
+		case *ast.IntegerLiteral, *ast.FloatLiteral:
+			return pattern.Operator == "-"
+		}
+	}
+
+	return ast.IsWildcard(pattern)
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.curToken}
+	block.Statements = []ast.Statement{}
+
+	depth := p.nestingDepth
+	defer func() { p.nestingDepth = depth }()
+
+	// isBlockStart only needs to know where the block ends, what's inside of it can't change that
+	if !p.enterNesting() || p.recording > 0 {
+		p.skipBlock()
+		return block
+	}
+
+	// Jump over the LBRACE token
+	p.nextToken()
+	// Continue parsing the statement until we reach the end of the block or token.EOF
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+		stmt := p.parseStatementWithRecovery()
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	return block
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	func_lit := &ast.FunctionLiteral{Token: p.curToken}
+
+	if !p.parseFunction(func_lit) {
+		return nil
+	}
+
+	return func_lit
+}
+
+// fn name(params) { body }
+func (p *Parser) parseFunctionStatement() *ast.FunctionStatement {
+	stmt := &ast.FunctionStatement{Token: p.curToken}
+
+	// sitting on the name
+	p.nextToken()
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	stmt.Function = &ast.FunctionLiteral{Token: stmt.Token, Name: stmt.Name.Value}
+
+	if !p.parseFunction(stmt.Function) {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// Parses the (params) { body } part of a function, reports whether it was valid
+func (p *Parser) parseFunction(func_lit *ast.FunctionLiteral) bool {
+	if !p.expectPeek(token.LPAREN) {
+		return false
+	}
+
+	if !p.parseFunctionParameters(func_lit) || !p.expectPeek(token.LBRACE) {
+		return false
+	}
+
+	// a break inside of a function can't stop a loop outside of it
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
+	func_lit.Body = p.parseBlockStatement()
+	p.loopDepth = loopDepth
+
+	return true
+}
+
+/**
+Parses a function's parameter list into the function literal, reports whether it was valid.
+(x, y)
+(x, y = 10)        default values get evaluated on every call that doesn't pass the argument
+(first, ...rest)   the rest parameter collects the extra arguments into an array, it has to be the last one
+**/
+func (p *Parser) parseFunctionParameters(func_lit *ast.FunctionLiteral) bool {
+	func_lit.Parameters = []*ast.Identifier{}
+	func_lit.Defaults = map[string]ast.Expression{}
+	seen := map[string]bool{}
+
+	//empty parameter list
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return true
+	}
+
+	for {
+		// ...rest
+		isRest := p.peekTokenIs(token.ELLIPSIS)
+		if isRest {
+			p.nextToken()
+		}
+
+		// Move past the parenthesis / comma we're currently on, point to the identifier
+		if !p.expectPeek(token.IDENT) {
+			return false
+		}
+
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if seen[ident.Value] {
+			p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_PARAMETER, p.curToken, "duplicate parameter %s", ident.Value))
+			return false
+		}
+		seen[ident.Value] = true
+
+		if isRest {
+			func_lit.Rest = ident
+
+			if !p.peekTokenIs(token.RPAREN) {
+				p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_PARAMETER, p.curToken, "rest parameter %s must be the last parameter", ident.Value).
+					WithHint("move ...%s to the end of the parameter list", ident.Value))
+				return false
+			}
+
+			break
+		}
+
+		func_lit.Parameters = append(func_lit.Parameters, ident)
+
+		// x = <default value>
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+
+			value := p.parseExpression(LOWEST)
+
+			if value == nil {
+				return false
+			}
+
+			func_lit.Defaults[ident.Value] = value
+		} else if len(func_lit.Defaults) > 0 {
+			// fn(a = 1, b): calling it with one argument would leave b without a value
+			p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_PARAMETER, p.curToken, "required parameter %s follows a parameter with a default value", ident.Value).
+				WithHint("parameters with defaults must come after the ones without"))
+			return false
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+
+		p.nextToken()
+	}
+
+	// no closing parenthesis
+	return p.expectPeek(token.RPAREN)
+}
+
+// recieves the already parsed function as argument, uses it to construct call expression node.
+// "leftExp" in parseExpressions gets passed to this infix parsing function
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	/*
+		p.curToken => left parenthesis
+		function => identifier (i.e.: add, subtract, doTheThing)
+	*/
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	// (x,y,z), (x, y: 2)
+	if !p.parseCallArguments(exp) {
+		return nil
+	}
+
+	return exp
+}
+
+/**
+Parses the arguments of a call, positional ones first and then the named ones:
+add(1, 2)
+add(1, y: 2)
+add(y: 2, x: 1)
+**/
+func (p *Parser) parseCallArguments(exp *ast.CallExpression) bool {
+	exp.Arguments = []ast.Expression{}
+	exp.NamedArguments = []*ast.NamedArgument{}
+	seen := map[string]bool{}
+
+	// no arguments
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return true
+	}
+
+	for {
+		p.nextToken()
+
+		// name: value
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			arg := &ast.NamedArgument{Token: p.curToken, Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+
+			if seen[arg.Name.Value] {
+				p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_ARGUMENT, p.curToken, "argument %s is passed more than once", arg.Name.Value))
+				return false
+			}
+			seen[arg.Name.Value] = true
+
+			p.nextToken()
+			p.nextToken()
+			arg.Value = p.parseExpression(LOWEST)
+
+			if arg.Value == nil {
+				return false
+			}
+
+			exp.NamedArguments = append(exp.NamedArguments, arg)
+		} else {
+			if len(exp.NamedArguments) > 0 {
+				p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_ARGUMENT, p.curToken, "positional argument after a named argument").
+					WithHint("pass positional arguments first: f(1, 2, name: 3)"))
+				return false
+			}
+
+			arg := p.parseExpression(LOWEST)
+
+			if arg == nil {
+				return false
+			}
+
+			exp.Arguments = append(exp.Arguments, arg)
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+
+		p.nextToken()
+	}
+
+	return p.expectPeek(token.RPAREN)
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+/**
+Parses an interpolated string, the lexer gives us the text and the tokens of the embedded expressions:
+INTERP_START <expression> INTERP_MID <expression> ... INTERP_END
+**/
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+
+	for {
+		p.nextToken()
+
+		exp := p.parseExpression(LOWEST)
+		if exp == nil {
+			return nil
+		}
+		str.Parts = append(str.Parts, exp)
+
+		// the rest of the string is broken (never closed, bad escapes, etc), report what the lexer found
+		if p.peekTokenIs(token.ILLEGAL) {
+			p.nextToken()
+			return p.parseIllegal()
+		}
+
+		if !p.peekTokenIs(token.INTERP_MID) && !p.peekTokenIs(token.INTERP_END) {
+			p.addDiagnostic(diagnostic.NewError(diagnostic.UNEXPECTED_TOKEN, p.peekToken, "expected } to close the interpolation, got %s instead", p.peekToken.Type).
+				WithHint("interpolations can only contain a single expression: \"${a + b}\""))
+			return nil
+		}
+
+		p.nextToken()
+		str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+
+		if p.curTokenIs(token.INTERP_END) {
+			return str
+		}
+	}
+}
+
+// parses a list of expressions until we reach the end of the list (via the end token type)
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	// empty list
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+
+	// first expression
+	p.nextToken()
+	exp := p.parseExpression(LOWEST)
+
+	if exp == nil {
+		return nil
+	}
+
+	list = append(list, exp)
+
+	// For each comma separated expression
+	for p.peekTokenIs(token.COMMA) {
+		// move up 2 tokens (so we land on the expressoin)
+		p.nextToken()
+		p.nextToken()
+		// parse the expression
+		exp := p.parseExpression(LOWEST)
+
+		if exp == nil {
+			return nil
+		}
+
+		list = append(list, exp)
+	}
+
+	// If for some reason we haven't reached the end token we passed, return nil
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return list
+
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	// the [ token
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	// Grab all the elements before we reach the right bracket (end of array)
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+
+	if array.Elements == nil {
+		return nil
+	}
+
+	return array
+}
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	/**
@@ -652,7 +1368,7 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp.Index = index
 
 	// we should reach a ] after the index expression
-	if !p.expectPeek(token.RBRACKET) {
+	if index == nil || !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
 
@@ -687,12 +1403,16 @@ func (p *Parser) parseIndexAssignment(node, index ast.Expression) ast.Expression
 	// we should now have some value to parse
 	value := p.parseExpression(LOWEST)
 
+	if value == nil {
+		return nil
+	}
+
 	return &ast.IndexAssignment{Left: identifier, Index: index, Token: token, Value: value}
 }
 
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken} // the { symbol
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	hash.Pairs = []ast.HashLiteralPair{}
 
 	// Until we reach an } (the end of the hash)
 	for !p.peekTokenIs(token.RBRACE) {
@@ -701,18 +1421,21 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		key := p.parseExpression(LOWEST)
 
 		// A key should be followed by a : (ex: "key":"value")
-		if !p.peekTokenIs(token.COLON) {
+		// note: expectPeek moves us onto the colon
+		if key == nil || !p.expectPeek(token.COLON) {
 			return nil
 		}
-		// most past colon :
-		p.nextToken()
 
 		//value
 		p.nextToken()
 		// grab the value
 		value := p.parseExpression(LOWEST)
+
+		if value == nil {
+			return nil
+		}
 		// create the pair
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{Key: key, Value: value})
 
 		// If we haven't reached a right brace (end of hash) or comma (next pair)
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
@@ -728,44 +1451,39 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	return hash
 }
 
-func (p *Parser) parseInternalCallExpression(left ast.Expression) ast.Expression {
-	// the current token should be '.'
-	if !p.curTokenIs(token.DOT) {
-		return nil
-	}
+/**
+Parses whatever comes after a '.':
+- arr.pop(), [1, 2].map(f), person["tags"].first() => method call, the left side is passed as the first argument
+- person.name => property access on a hash, same as person["name"]
 
+The left side can be any expression, calls chain from left to right: arr.map(f).slice(1)
+**/
+func (p *Parser) parseInternalCallExpression(left ast.Expression) ast.Expression {
 	dot := p.curToken
 
-	// Grab the identifier: arr, hash, etc.
-	ident, ok := left.(*ast.Identifier)
-
-	if !ok {
+	// We should now be at the function / property name: pop, delete, name, etc
+	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 
-	// We should now be at the function name: pop, delete, etc
-	p.nextToken()
-	internal_function_ident := p.parseIdentifier()
-	func_ident, ok := internal_function_ident.(*ast.Identifier)
+	func_ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	if !ok {
-		return nil
+	// no '(' after the name, it's a property
+	if !p.peekTokenIs(token.LPAREN) {
+		return &ast.PropertyExpression{Token: dot, Left: left, Property: func_ident}
 	}
 
-	//After the function name we should expect a '('
-	if !p.expectPeek(token.LPAREN) {
-		return nil
-	}
+	p.nextToken()
 
 	// After the '(' we should have either 0 -> expressions
 	args := p.parseExpressionList(token.RPAREN)
 
-	if !ok {
+	if args == nil {
 		return nil
 	}
 
 	ifc := &ast.InternalFunctionCall{
-		CallerIdentifier:   ident,
+		Caller:             left,
 		Token:              dot,
 		FunctionIdentifier: func_ident,
 		Arguments:          args,
@@ -774,11 +1492,6 @@ func (p *Parser) parseInternalCallExpression(left ast.Expression) ast.Expression
 }
 
 func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
-	// the current token should be '='
-	if !p.curTokenIs(token.ASSIGN) {
-		return nil
-	}
-
 	assignment := &ast.AssignmentExpression{}
 
 	assign := p.curToken
@@ -789,6 +1502,8 @@ func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
 	ident, ok := left.(*ast.Identifier)
 
 	if !ok {
+		p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_ASSIGNMENT_TARGET, assign, "cannot assign to %s", left.String()).
+			WithHint("only variables (x = 1) and indexes (arr[0] = 1, hash[key] = 1) can be assigned to"))
 		return nil
 	}
 
@@ -798,6 +1513,10 @@ func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
 	p.nextToken()
 	assignment.Value = p.parseExpression(LOWEST)
 
+	if assignment.Value == nil {
+		return nil
+	}
+
 	// If we reach a semicolon (terminating the expression), move onto the next token.
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
@@ -807,34 +1526,51 @@ func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
 
 }
 
-func (p *Parser) parseForLoopStatement() *ast.ForLoopStatement {
+/**
+Both kinds of for loops start with 'for (', the token after that tells us which one we're parsing:
+- for (let i = 0; i < 10; i = i + 1) { ... }
+- for (x in arr) { ... }, for (k, v in hash) { ... }
+**/
+func (p *Parser) parseForStatement() ast.Statement {
 	// the current token value here should be 'for'
-	if !p.curTokenIs(token.FOR) {
+	forToken := p.curToken
+
+	// Lets make sure the next token is an LPAREN '('
+	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
 
-	forToken := p.curToken
+	if p.peekTokenIs(token.IDENT) {
+		if loop := p.parseForInStatement(forToken); loop != nil {
+			return loop
+		}
+		return nil
+	}
 
-	loop := &ast.ForLoopStatement{
-		Token: forToken,
+	if loop := p.parseForLoopStatement(forToken); loop != nil {
+		return loop
 	}
+	return nil
+}
 
-	// Lets make sure the next token is an LPAREN '('
-	p.nextToken()
-	if !p.curTokenIs(token.LPAREN) {
-		return nil
+// for (let i = 0; i < 10; i = i + 1) { ... }, the current token should be the '('
+func (p *Parser) parseForLoopStatement(forToken token.Token) *ast.ForLoopStatement {
+	loop := &ast.ForLoopStatement{
+		Token: forToken,
 	}
 
 	// Move onto the next token
 	// we should now be at the LET statement
 
 	// for ( let x = 0
-	p.nextToken()
-	counterVar := p.parseStatement()
+	if !p.expectPeek(token.LET) {
+		p.forLoopHint()
+		return nil
+	}
 
-	letStatement, ok := counterVar.(*ast.LetStatement)
+	letStatement := p.parseLetStatement()
 
-	if !ok {
+	if letStatement == nil {
 		return nil
 	}
 
@@ -842,64 +1578,57 @@ func (p *Parser) parseForLoopStatement() *ast.ForLoopStatement {
 
 	// for ( let x = 0 ;
 	if !p.curTokenIs(token.SEMICOLON) {
+		p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_FOR_LOOP, p.peekToken, "expected ; after the for loop counter, got %s instead", p.peekToken.Type))
+		p.forLoopHint()
 		return nil
 	}
 
 	// x < 10
 	p.nextToken()
-	ident := p.parseIdentifier()
-
-	identifier, ok := ident.(*ast.Identifier)
+	conditionToken := p.curToken
+	loopCondition := p.parseExpression(LOWEST)
 
-	if !ok {
+	if loopCondition == nil {
 		return nil
 	}
 
-	p.nextToken()
-	loopCondition := p.parseInfixExpression(identifier)
-
-	condition, ok := loopCondition.(*ast.InfixExpression)
-
-	if !ok {
+	// x < 10, x < 10 && y > 2, etc
+	switch loopCondition.(type) {
+	case *ast.InfixExpression, *ast.LogicalExpression:
+		loop.LoopCondition = loopCondition
+	default:
+		p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_FOR_LOOP, conditionToken, "for loop condition must be a comparison, got %s", loopCondition.String()))
+		p.forLoopHint()
 		return nil
 	}
 
-	loop.LoopCondition = condition
-
 	// for ( let x = 0 ; x < 10 ;
-	if !p.peekTokenIs(token.SEMICOLON) {
+	if !p.expectPeek(token.SEMICOLON) {
 		return nil
 	}
 
-	p.nextToken()
-
 	// this should be pointing at 'x' now.
 	// x = x + 1
-	p.nextToken()
-
-	if !p.curTokenIs(token.IDENT) {
+	if !p.expectPeek(token.IDENT) {
+		p.forLoopHint()
 		return nil
 	}
 
-	ident = p.parseIdentifier()
+	identifier := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	identifier, ok = ident.(*ast.Identifier)
-
-	if !ok {
+	// =
+	if !p.expectPeek(token.ASSIGN) {
+		p.forLoopHint()
 		return nil
 	}
 
-	// =
-	p.nextToken()
 	updateCounter := p.parseAssignmentExpression(identifier)
-	// lets make sure this is an assignment expression
-	counterUpdate, ok := updateCounter.(*ast.AssignmentExpression)
 
-	if !ok {
+	if updateCounter == nil {
 		return nil
 	}
 
-	loop.CounterUpdate = counterUpdate
+	loop.CounterUpdate = updateCounter.(*ast.AssignmentExpression)
 	// for ( let x = 0; x < 10; x = x + 1 )
 	if !p.expectPeek(token.RPAREN) {
 		return nil
@@ -911,7 +1640,7 @@ func (p *Parser) parseForLoopStatement() *ast.ForLoopStatement {
 	}
 
 	// for ( let x = 0; x < 10; x = x + 1 ) { puts x
-	body := p.parseBlockStatement()
+	body := p.parseLoopBody()
 	loop.LoopBlock = body
 
 	// for ( let x = 0; x < 10; x = x + 1 ) { puts x };
@@ -923,6 +1652,211 @@ func (p *Parser) parseForLoopStatement() *ast.ForLoopStatement {
 	return loop
 }
 
+/**
+for (x in arr) { ... }
+for (k, v in hash) { ... }
+
+the current token should be the '('
+**/
+func (p *Parser) parseForInStatement(forToken token.Token) *ast.ForInStatement {
+	loop := &ast.ForInStatement{Token: forToken}
+
+	// for (x
+	p.nextToken()
+	loop.Variables = append(loop.Variables, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+	// for (k, v
+	if p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		loop.Variables = append(loop.Variables, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+
+	// for (x in
+	if !p.expectPeek(token.IN) {
+		p.forLoopHint()
+		return nil
+	}
+
+	// for (x in arr
+	p.nextToken()
+	loop.Iterable = p.parseExpression(LOWEST)
+
+	if loop.Iterable == nil || !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	// for (x in arr) { ... }
+	loop.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return loop
+}
+
+// Reminds the user what a for loop looks like, attached to the last reported diagnostic
+func (p *Parser) forLoopHint() {
+	if len(p.diagnostics) == 0 {
+		return
+	}
+
+	p.diagnostics[len(p.diagnostics)-1].WithHint("for loops look like: for (let i = 0; i < 10; i = i + 1) { ... } or for (x in arr) { ... }")
+}
+
+// while (x < 10) { ... }
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if stmt.Condition == nil || !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// Parses the block of a loop, break and continue are only allowed in here
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	body := p.parseBlockStatement()
+	p.loopDepth--
+
+	return body
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+
+	if !p.checkInsideLoop() {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+
+	if !p.checkInsideLoop() {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// Reports break / continue statements that aren't inside of a loop
+func (p *Parser) checkInsideLoop() bool {
+	if p.loopDepth > 0 {
+		return true
+	}
+
+	p.addDiagnostic(diagnostic.NewError(diagnostic.OUTSIDE_LOOP, p.curToken, "%s outside of a loop", p.curToken.Literal).
+		WithHint("%s can only be used inside of a for or while loop body", p.curToken.Literal))
+
+	return false
+}
+
+// try { ... } catch (e) { ... } finally { ... }
+func (p *Parser) parseTryStatement() *ast.TryStatement {
+	stmt := &ast.TryStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		// catch (e), the name is optional
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+
+			stmt.CatchParam = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		stmt.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		stmt.Finally = p.parseBlockStatement()
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		p.addDiagnostic(diagnostic.NewError(diagnostic.INVALID_TRY, stmt.Token, "try without catch or finally").
+			WithHint("add a catch block: try { ... } catch (e) { ... }"))
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// throw <expression>;
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if stmt.Value == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 /**
 Dev Notes:
 
@@ -2,8 +2,11 @@ package parser
 
 import (
 	"boar/ast"
+	"boar/diagnostic"
 	"boar/lexer"
+	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 )
 
@@ -42,6 +45,27 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestConstStatements(t *testing.T) {
+	program := New(lexer.New("const limit = 10; let x = limit;")).ParseProgram()
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	constStmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok || !constStmt.Const || constStmt.Name.Value != "limit" {
+		t.Fatalf("expected a const statement for limit, got %+v", program.Statements[0])
+	}
+
+	if constStmt.String() != "const limit = 10;" {
+		t.Errorf("wrong String() for the const statement, got %q", constStmt.String())
+	}
+
+	if program.Statements[1].(*ast.LetStatement).Const {
+		t.Errorf("expected a let statement to not be const")
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -196,6 +220,43 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5;", 1.5},
+		{".5;", 0.5},
+		{"1e-3;", 0.001},
+		{"2.5E+2;", 250},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. go=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not an ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %f. got=%f", tt.expected, literal.Value)
+		}
+	}
+}
+
 func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
 	integ, ok := il.(*ast.IntegerLiteral)
 
@@ -301,6 +362,10 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"5 < 5;", 5, "<", 5},
 		{"5 == 5;", 5, "==", 5},
 		{"5 != 5;", 5, "!=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"5 ** 5;", 5, "**", 5},
 	}
 
 	for _, tt := range infixTests {
@@ -438,6 +503,38 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a + b % c",
+			"(a + (b % c))",
+		},
+		{
+			"a <= b == c >= d",
+			"((a <= b) == (c >= d))",
+		},
+		{
+			"a * b ** c",
+			"(a * (b ** c))",
+		},
+		{
+			"a ** b ** c",
+			"(a ** (b ** c))",
+		},
+		{
+			"-a ** b",
+			"(-(a ** b))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a < b && c == d || !e",
+			"(((a < b) && (c == d)) || (!e))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -733,6 +830,162 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestOptionalAndRestParameterParsing(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedParams   []string
+		expectedDefaults map[string]string
+		expectedRest     string
+		expectedString   string
+	}{
+		{"fn(x, y = 10) {};", []string{"x", "y"}, map[string]string{"y": "10"}, "", "fn(x, y = 10) "},
+		{"fn(x = 1, y = x * 2) {};", []string{"x", "y"}, map[string]string{"x": "1", "y": "(x * 2)"}, "", "fn(x = 1, y = (x * 2)) "},
+		{"fn(...rest) {};", []string{}, map[string]string{}, "rest", "fn(...rest) "},
+		{"fn(first, y = 2, ...rest) {};", []string{"first", "y"}, map[string]string{"y": "2"}, "rest", "fn(first, y = 2, ...rest) "},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("length parameters wrong. wanted %d, got=%d\n", len(tt.expectedParams), len(function.Parameters))
+		}
+
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+
+		if len(function.Defaults) != len(tt.expectedDefaults) {
+			t.Errorf("wrong number of default values. wanted %d, got=%d", len(tt.expectedDefaults), len(function.Defaults))
+		}
+
+		for name, value := range tt.expectedDefaults {
+			if function.Defaults[name] == nil || function.Defaults[name].String() != value {
+				t.Errorf("wrong default value for %s. wanted %q, got=%v", name, value, function.Defaults[name])
+			}
+		}
+
+		rest := ""
+		if function.Rest != nil {
+			rest = function.Rest.Value
+		}
+
+		if rest != tt.expectedRest {
+			t.Errorf("wrong rest parameter. wanted %q, got=%q", tt.expectedRest, rest)
+		}
+
+		if function.String() != tt.expectedString {
+			t.Errorf("wrong String(). wanted %q, got=%q", tt.expectedString, function.String())
+		}
+	}
+}
+
+func TestNamedArgumentParsing(t *testing.T) {
+	tests := []struct {
+		input              string
+		expectedPositional []string
+		expectedNamed      []string
+	}{
+		{"add(1, y: 2)", []string{"1"}, []string{"y: 2"}},
+		{"add(y: 2 * 3, x: {\"a\": 1})", []string{}, []string{"y: (2 * 3)", "x: {a:1}"}},
+		{"add(a, b)", []string{"a", "b"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		call, ok := stmt.Expression.(*ast.CallExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+		}
+
+		if len(call.Arguments) != len(tt.expectedPositional) {
+			t.Fatalf("wrong number of positional arguments. wanted %d, got=%d", len(tt.expectedPositional), len(call.Arguments))
+		}
+
+		for i, arg := range tt.expectedPositional {
+			if call.Arguments[i].String() != arg {
+				t.Errorf("wrong argument. wanted %q, got=%q", arg, call.Arguments[i].String())
+			}
+		}
+
+		if len(call.NamedArguments) != len(tt.expectedNamed) {
+			t.Fatalf("wrong number of named arguments. wanted %d, got=%d", len(tt.expectedNamed), len(call.NamedArguments))
+		}
+
+		for i, arg := range tt.expectedNamed {
+			if call.NamedArguments[i].String() != arg {
+				t.Errorf("wrong named argument. wanted %q, got=%q", arg, call.NamedArguments[i].String())
+			}
+		}
+	}
+}
+
+func TestParameterAndArgumentErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(...rest, x) {}", "1:7: rest parameter rest must be the last parameter"},
+		{"fn(x, x) {}", "1:7: duplicate parameter x"},
+		{"fn(x, ...x) {}", "1:10: duplicate parameter x"},
+		{"fn(x = ) {}", "1:8: no prefix parse function for ) found"},
+		{"fn(a = 1, b) {}", "1:11: required parameter b follows a parameter with a default value"},
+		{"fn(a, b = 1, c, ...rest) {}", "1:14: required parameter c follows a parameter with a default value"},
+		{"fn(...) {}", "1:7: expected next token to be IDENT, got ) instead"},
+		{"add(x: 1, 2)", "1:11: positional argument after a named argument"},
+		{"add(x: 1, x: 2)", "1:11: argument x is passed more than once"},
+		{"add(x: )", "1:8: no prefix parse function for ) found"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+
+		if len(errors) == 0 {
+			t.Errorf("expected errors for %q, got none", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error for %q, expected %q, got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestDefaultParameterOrder(t *testing.T) {
+	p := New(lexer.New("fn f(a = 1, b) { a + b }"))
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d: %v", len(diagnostics), diagnostics)
+	}
+
+	d := diagnostics[0]
+	if d.Code != diagnostic.INVALID_PARAMETER {
+		t.Errorf("wrong code, expected %s, got %s", diagnostic.INVALID_PARAMETER, d.Code)
+	}
+
+	expectedHint := "parameters with defaults must come after the ones without"
+	if len(d.Hints) != 1 || d.Hints[0] != expectedHint {
+		t.Errorf("wrong hints, got %v", d.Hints)
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -937,22 +1190,28 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has the wrong length. Expected 3, got %d", len(hash.Pairs))
 	}
 
-	expected := map[string]int64{
-		"one":   1,
-		"two":   2,
-		"three": 3,
+	// in the order they were written in
+	expected := []struct {
+		key   string
+		value int64
+	}{
+		{"one", 1},
+		{"two", 2},
+		{"three", 3},
 	}
 
-	for key, val := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for idx, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral, got %d", key)
+			t.Fatalf("key is not ast.StringLiteral, got %T", pair.Key)
 		}
 
-		expectedValue := expected[literal.String()]
+		if literal.String() != expected[idx].key {
+			t.Errorf("wrong key at %d, expected %s, got %s", idx, expected[idx].key, literal.String())
+		}
 
-		testIntegerLiteral(t, val, expectedValue)
+		testIntegerLiteral(t, pair.Value, expected[idx].value)
 	}
 }
 
@@ -976,6 +1235,82 @@ func TestParsingEmptyHashLiteral(t *testing.T) {
 	}
 }
 
+func TestBlockStatementVersusHash(t *testing.T) {
+	tests := []struct {
+		input   string
+		isBlock bool
+	}{
+		{"{}", false},
+		{`{"a": 1}`, false},
+		{"{key: value}", false},
+		{"{ {\"a\": 1}: 2 }", false},
+		{"{ let x = 1; x }", true},
+		{"{ x }", true},
+		{"{ f(a, b) }", true},
+		{"{ x = {\"a\": 1} }", true},
+		{"{ if (x) { 1 } }", true},
+		{"{ { let y = 2 } };", true},
+		{"{ 1 }", true},
+		{`{ "a" }`, true},
+		{`{ "a" 1 }`, true},
+		{"{ true; 2 }", true},
+		{`{ "a" + "b": 1 }`, false},
+		{"{ 1: 2, 3: 4 }", false},
+		{"{ x\n y }", true},
+		{"{ fn(a) { { a } }(1) }", true},
+		{"{ fn(a) { a }: 1 }", false},
+		{"{ { 1 }; 2 }", true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement for %q. got=%d", tt.input, len(program.Statements))
+		}
+
+		_, isBlock := program.Statements[0].(*ast.BlockStatement)
+		if isBlock != tt.isBlock {
+			t.Errorf("wrong statement for %q, expected a block: %t, got %T", tt.input, tt.isBlock, program.Statements[0])
+		}
+	}
+}
+
+// Only the token after the first expression decides, a : further down is an error in the block
+func TestBlockStartLooksAtFirstExpression(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+	}{
+		{"{ x; y: 1 }", []string{"1:7: no prefix parse function for : found"}},
+		{"{ puts(1)\n y: 2 }", []string{"2:3: no prefix parse function for : found"}},
+		// errors found while looking ahead are only reported once, by the real parse
+		{"{ 1 + ; 2 }", []string{"1:7: no prefix parse function for ; found"}},
+		{"{ fn() { let = 1 } }", []string{"1:14: expected next token to be IDENT, got = instead"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		if len(program.Statements) == 0 {
+			t.Fatalf("no statements for %q", tt.input)
+		}
+		if _, ok := program.Statements[0].(*ast.BlockStatement); !ok {
+			t.Errorf("expected a block for %q, got %T", tt.input, program.Statements[0])
+		}
+
+		errors := p.Errors()
+		if strings.Join(errors, "\n") != strings.Join(tt.expectedErrors, "\n") {
+			t.Errorf("wrong errors for %q, expected %q, got %q", tt.input, tt.expectedErrors, errors)
+		}
+	}
+}
+
 func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 	input := `{"one": 0 + 1, "two": 10 - 8, "three": 15 / 5}`
 
@@ -1007,10 +1342,10 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 			continue
 		}
 
@@ -1019,7 +1354,7 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 			t.Errorf("No test function for key %q found", literal.String())
 			continue
 		}
-		testFunc(value)
+		testFunc(pair.Value)
 	}
 }
 
@@ -1051,12 +1386,12 @@ func TestParsingInternalFunctionCalls(t *testing.T) {
 		t.Fatalf("Invalid token for *ast.InternalFunctionCall, expected '%s', got '%s'", ".", ifc.Token.Literal)
 	}
 
-	if !testIdentifier(t, ifc.CallerIdentifier, "arr") {
-		t.Fatalf("Invalid identifier, expected %s, got %s", "arr", ifc.CallerIdentifier)
+	if !testIdentifier(t, ifc.Caller, "arr") {
+		t.Fatalf("Invalid identifier, expected %s, got %s", "arr", ifc.Caller)
 	}
 
 	if !testIdentifier(t, ifc.FunctionIdentifier, "slice") {
-		t.Fatalf("Invalid identifier, expected %s, got %s", "slice", ifc.CallerIdentifier)
+		t.Fatalf("Invalid identifier, expected %s, got %s", "slice", ifc.FunctionIdentifier)
 	}
 
 	if len(ifc.Arguments) != 2 {
@@ -1172,3 +1507,979 @@ func TestForLoopStatements(t *testing.T) {
 		}
 	}
 }
+
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let x 5;", "1:7: expected next token to be =, got INT instead"},
+		{"let x = 1;\nlet = 2;", "2:5: expected next token to be IDENT, got = instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q, got none", tt.input)
+		}
+
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error, expected %q, got %q", tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestParserDiagnostics(t *testing.T) {
+	input := "let x = 1;\nlet y 5;"
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+
+	if len(diagnostics) == 0 {
+		t.Fatalf("expected parser diagnostics, got none")
+	}
+
+	d := diagnostics[0]
+
+	if d.Code != diagnostic.UNEXPECTED_TOKEN {
+		t.Errorf("wrong diagnostic code, expected %s, got %s", diagnostic.UNEXPECTED_TOKEN, d.Code)
+	}
+
+	if d.Severity != diagnostic.ERROR {
+		t.Errorf("wrong severity, expected %s, got %s", diagnostic.ERROR, d.Severity)
+	}
+
+	if d.Span.Start.Line != 2 || d.Span.Start.Column != 7 || d.Span.End.Column != 8 {
+		t.Errorf("wrong span, got %s - %s", d.Span.Start, d.Span.End)
+	}
+}
+
+// The carets cover the string as it's written in the source, not its value (quotes and escapes included)
+func TestRenderEscapedString(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedLines []string
+	}{
+		{`let x "a\tb";`, []string{"1 | let x \"a\\tb\";", "  |       ^^^^^^"}},
+		{`let x "\u{e9}${1}";`, []string{"1 | let x \"\\u{e9}${1}\";", "  |       ^^^^^^^^^"}},
+		{"let x `a\\nb`;", []string{"1 | let x `a\\nb`;", "  |       ^^^^^^"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) != 1 {
+			t.Fatalf("expected 1 diagnostic for %q, got %q", tt.input, p.Errors())
+		}
+
+		var out bytes.Buffer
+		diagnostic.Render(&out, tt.input, diagnostics[0])
+
+		lines := strings.Split(out.String(), "\n")
+		if len(lines) < 5 || lines[3] != tt.expectedLines[0] || lines[4] != tt.expectedLines[1] {
+			t.Errorf("wrong render output for %q, got:\n%s", tt.input, out.String())
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedErrors []string
+	}{
+		{
+			"let x 5;\nlet y = 2;\nlet = 3;\nlet z = 4;",
+			[]string{
+				"1:7: expected next token to be =, got INT instead",
+				"3:5: expected next token to be IDENT, got = instead",
+			},
+		},
+		{
+			"let f = fn(x) { let = 5; x };\nlet y = ;",
+			[]string{
+				"1:21: expected next token to be IDENT, got = instead",
+				"2:9: no prefix parse function for ; found",
+			},
+		},
+		{
+			"if (x { let y = 1; }\nlet z = ;",
+			[]string{
+				"1:7: expected next token to be ), got { instead",
+				"2:9: no prefix parse function for ; found",
+			},
+		},
+		{
+			"for (x = 0; x < 10; x = x + 1) { puts(x) };\nlet y;",
+			[]string{
+				"1:8: expected next token to be IN, got = instead",
+				"2:6: expected next token to be =, got ; instead",
+			},
+		},
+		{
+			"if (true) { let x = } else { 2 }; let q;",
+			[]string{
+				"1:21: no prefix parse function for } found",
+				"1:40: expected next token to be =, got ; instead",
+			},
+		},
+		{
+			"let x 5\nconst = 2\nlet y = 1;",
+			[]string{
+				"1:7: expected next token to be =, got INT instead",
+				"2:7: expected next token to be IDENT, got = instead",
+			},
+		},
+		{
+			"let x 5\nfn add(a, b) { let = a + b }",
+			[]string{
+				"1:7: expected next token to be =, got INT instead",
+				"2:20: expected next token to be IDENT, got = instead",
+			},
+		},
+		{
+			"let f = map([1], fn(x) { x +, y });\nlet = 1;",
+			[]string{
+				"1:29: no prefix parse function for , found",
+				"2:5: expected next token to be IDENT, got = instead",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+
+		if len(errors) != len(tt.expectedErrors) {
+			t.Errorf("wrong number of errors for %q, expected %d, got %d: %q", tt.input, len(tt.expectedErrors), len(errors), errors)
+			continue
+		}
+
+		for i, expected := range tt.expectedErrors {
+			if errors[i] != expected {
+				t.Errorf("wrong error, expected %q, got %q", expected, errors[i])
+			}
+		}
+	}
+}
+
+func TestParserReportsSilentFailures(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedCode diagnostic.Code
+	}{
+		{`let h = {"a" 1}`, diagnostic.UNEXPECTED_TOKEN},
+		{"for (let x = 0; 5; x = x + 1) { x };", diagnostic.INVALID_FOR_LOOP},
+		{"for (let x = 0; x < 10; 1) { x };", diagnostic.UNEXPECTED_TOKEN},
+		{"1 = 2;", diagnostic.INVALID_ASSIGNMENT_TARGET},
+		{"arr.pop(;", diagnostic.NO_PREFIX_PARSE_FN},
+		{"arr.5();", diagnostic.UNEXPECTED_TOKEN},
+		{"fn(1, y) { y };", diagnostic.UNEXPECTED_TOKEN},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		diagnostics := p.Diagnostics()
+
+		if len(diagnostics) != 1 {
+			t.Errorf("expected 1 diagnostic for %q, got %d: %q", tt.input, len(diagnostics), p.Errors())
+			continue
+		}
+
+		if diagnostics[0].Code != tt.expectedCode {
+			t.Errorf("wrong diagnostic code for %q, expected %s, got %s", tt.input, tt.expectedCode, diagnostics[0].Code)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	tests := []struct {
+		input        string
+		emitComments bool
+		expected     string
+	}{
+		{"// nothing to see here\nlet x = 5; // five\nx", false, "let x = 5;x"},
+		{"let /* inline */ x = 10 / 2;", false, "let x = (10 / 2);"},
+		{"let x = 1; /* a /* nested */ comment */ x", true, "let x = 1;x"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		l.EmitComments(tt.emitComments)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestIllegalTokenDiagnostics(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedCode    diagnostic.Code
+		expectedMessage string
+	}{
+		{"let x = 1;\n/* never closed", diagnostic.UNTERMINATED_COMMENT, "2:1: unterminated block comment"},
+		{"let x = #;", diagnostic.ILLEGAL_CHARACTER, "1:9: illegal character \"#\""},
+		{"let x = \"oops;\nlet y = 1;", diagnostic.UNTERMINATED_STRING, "1:9: unterminated string literal"},
+		{`let x = "a\qb";`, diagnostic.INVALID_ESCAPE, "1:11: unknown escape sequence \\q"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		diagnostics := p.Diagnostics()
+
+		if len(diagnostics) != 1 {
+			t.Errorf("expected 1 diagnostic for %q, got %d: %q", tt.input, len(diagnostics), p.Errors())
+			continue
+		}
+
+		if diagnostics[0].Code != tt.expectedCode {
+			t.Errorf("wrong diagnostic code for %q, expected %s, got %s", tt.input, tt.expectedCode, diagnostics[0].Code)
+		}
+
+		if diagnostics[0].String() != tt.expectedMessage {
+			t.Errorf("wrong diagnostic for %q, expected %q, got %q", tt.input, tt.expectedMessage, diagnostics[0].String())
+		}
+	}
+}
+
+func TestLogicalExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		left     interface{}
+		operator string
+		right    interface{}
+	}{
+		{"a && b;", "a", "&&", "b"},
+		{"true || false;", true, "||", false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. Got=%d\n", 1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("exp is not ast.ExpressionStatement. Got=%T", program.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.LogicalExpression)
+		if !ok {
+			t.Fatalf("exp is not ast.LogicalExpression. got=%T", stmt.Expression)
+		}
+
+		if !testLiteralExpression(t, exp.Left, tt.left) {
+			return
+		}
+
+		if exp.Operator != tt.operator {
+			t.Fatalf("exp.Operator is not %s, got=%q", tt.operator, exp.Operator)
+		}
+
+		if !testLiteralExpression(t, exp.Right, tt.right) {
+			return
+		}
+	}
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input         string
+		expected      string
+		expectedParts int
+	}{
+		{`"Hello ${name}!"`, `"Hello ${name}!"`, 3},
+		{`"${a + b * 2}"`, `"${(a + (b * 2))}"`, 3},
+		{`"${a} and ${b}"`, `"${a} and ${b}"`, 5},
+		{`"sum: ${add(1, "${x}")}"`, `"sum: ${add(1, "${x}")}"`, 3},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		str, ok := stmt.Expression.(*ast.InterpolatedString)
+		if !ok {
+			t.Fatalf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+		}
+
+		if len(str.Parts) != tt.expectedParts {
+			t.Errorf("wrong number of parts for %s, expected %d, got %d", tt.input, tt.expectedParts, len(str.Parts))
+		}
+
+		if str.String() != tt.expected {
+			t.Errorf("expected %s, got %s", tt.expected, str.String())
+		}
+	}
+}
+
+func TestInterpolatedStringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a ${x y} b"`, "1:8: expected } to close the interpolation, got IDENT instead"},
+		{`"a ${} b"`, "1:6: no prefix parse function for INTERP_END found"},
+		{`"a ${x} b`, "1:1: unterminated string literal"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+
+		if len(errors) == 0 {
+			t.Errorf("expected errors for %s, got none", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error for %s, expected %q, got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while (x < 10) { x = x + 1 }", "while((x < 10)){x=(x + 1);}"},
+		{"while (true) { break; };", "while(true){break;}"},
+		{"while (x) { if (x > 2) { continue } x }", "while(x){if(x > 2) continue;x}"},
+		{"for (let i = 0; i < 3; i = i + 1) { break }", "for(let i = 0;(i < 3);i=(i + 1);){break;};"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestBreakContinueOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"break;", []string{"1:1: break outside of a loop"}},
+		{"if (true) { continue }", []string{"1:13: continue outside of a loop"}},
+		{"while (true) { let f = fn() { break; }; }", []string{"1:31: break outside of a loop"}},
+		{"while (true) { break; }; continue; let x = 1;", []string{"1:26: continue outside of a loop"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q, expected %d, got %d: %q", tt.input, len(tt.expected), len(errors), errors)
+			continue
+		}
+
+		for i, expected := range tt.expected {
+			if errors[i] != expected {
+				t.Errorf("wrong error, expected %q, got %q", expected, errors[i])
+			}
+		}
+
+		for _, d := range p.Diagnostics() {
+			if d.Code != diagnostic.OUTSIDE_LOOP {
+				t.Errorf("wrong diagnostic code, expected %s, got %s", diagnostic.OUTSIDE_LOOP, d.Code)
+			}
+		}
+	}
+}
+
+func TestForInStatements(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedVariables []string
+		expectedIterable  string
+	}{
+		{"for (x in arr) { puts(x) }", []string{"x"}, "arr"},
+		{"for (k, v in hash) { puts(k) };", []string{"k", "v"}, "hash"},
+		{"for (ch in \"abc\") { break }", []string{"ch"}, "abc"},
+		{"for (i in range(0, 10)) { continue }", []string{"i"}, "range(0, 10)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		loop, ok := program.Statements[0].(*ast.ForInStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ForInStatement. got=%T", program.Statements[0])
+		}
+
+		if len(loop.Variables) != len(tt.expectedVariables) {
+			t.Fatalf("wrong number of loop variables, expected %d, got %d", len(tt.expectedVariables), len(loop.Variables))
+		}
+
+		for i, name := range tt.expectedVariables {
+			testIdentifier(t, loop.Variables[i], name)
+		}
+
+		if loop.Iterable.String() != tt.expectedIterable {
+			t.Errorf("wrong iterable, expected %q, got %q", tt.expectedIterable, loop.Iterable.String())
+		}
+
+		if loop.Body == nil || len(loop.Body.Statements) != 1 {
+			t.Errorf("loop body should have 1 statement")
+		}
+	}
+}
+
+func TestForInStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (x of arr) { x }", "1:8: expected next token to be IN, got IDENT instead"},
+		{"for (k, in hash) { k }", "1:9: expected next token to be IDENT, got IN instead"},
+		{"for (x in) { x }", "1:10: no prefix parse function for ) found"},
+		{"for (x in arr) x", "1:16: expected next token to be {, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+
+		if len(errors) == 0 {
+			t.Errorf("expected errors for %q, got none", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error for %q, expected %q, got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < 1) { a } else if (x < 2) { b } else { c }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("exp.Alternative.Statements does not contain 1 statement. got=%d", len(exp.Alternative.Statements))
+	}
+
+	alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", exp.Alternative.Statements[0])
+	}
+
+	nested, ok := alternative.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("alternative is not ast.IfExpression. got=%T", alternative.Expression)
+	}
+
+	if !testInfixExpression(t, nested.Condition, "x", "<", 2) {
+		return
+	}
+
+	if nested.Alternative == nil {
+		t.Fatalf("nested if expression is missing its else block")
+	}
+
+	expected := "if(x < 1) aelse if(x < 2) belse c"
+	if program.String() != expected {
+		t.Errorf("wrong String(), expected %q, got %q", expected, program.String())
+	}
+}
+
+func TestMatchExpression(t *testing.T) {
+	input := `match (x) { 1, 2 => "small", -3 => { "negative" }, _ if x > 10 => "big", _ => x }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Subject, "x") {
+		return
+	}
+
+	tests := []struct {
+		expectedPatterns []string
+		expectedGuard    string
+		expectedBody     string
+	}{
+		{[]string{"1", "2"}, "", "small"},
+		{[]string{"(-3)"}, "", "negative"},
+		{[]string{"_"}, "(x > 10)", "big"},
+		{[]string{"_"}, "", "x"},
+	}
+
+	if len(exp.Arms) != len(tests) {
+		t.Fatalf("wrong number of arms, expected %d, got %d", len(tests), len(exp.Arms))
+	}
+
+	for i, tt := range tests {
+		arm := exp.Arms[i]
+
+		if len(arm.Patterns) != len(tt.expectedPatterns) {
+			t.Errorf("arms[%d] - wrong number of patterns, expected %d, got %d", i, len(tt.expectedPatterns), len(arm.Patterns))
+			continue
+		}
+
+		for j, pattern := range tt.expectedPatterns {
+			if arm.Patterns[j].String() != pattern {
+				t.Errorf("arms[%d] - wrong pattern, expected %q, got %q", i, pattern, arm.Patterns[j].String())
+			}
+		}
+
+		guard := ""
+		if arm.Guard != nil {
+			guard = arm.Guard.String()
+		}
+
+		if guard != tt.expectedGuard {
+			t.Errorf("arms[%d] - wrong guard, expected %q, got %q", i, tt.expectedGuard, guard)
+		}
+
+		if arm.Body.String() != tt.expectedBody {
+			t.Errorf("arms[%d] - wrong body, expected %q, got %q", i, tt.expectedBody, arm.Body.String())
+		}
+	}
+
+	expected := `match(x) {1, 2 => small, (-3) => negative, _ if (x > 10) => big, _ => x}`
+	if program.String() != expected {
+		t.Errorf("wrong String(), expected %q, got %q", expected, program.String())
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { y => 1 }", "1:13: invalid match pattern: y"},
+		{"match (x) { 1 + 1 => 1 }", "1:13: invalid match pattern: (1 + 1)"},
+		{"match (x) { 1, [2] => 1 }", "1:16: invalid match pattern: [2]"},
+		{"match (x) { 1 2 }", "1:15: expected next token to be =>, got INT instead"},
+		{"match (x) { 1 => 1 2 => 2 }", "1:20: expected next token to be ,, got INT instead"},
+		{"match x { 1 => 1 }", "1:7: expected next token to be (, got IDENT instead"},
+		{"match (x) { 1 => }", "1:18: no prefix parse function for } found"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+
+		if len(errors) == 0 {
+			t.Errorf("expected errors for %q, got none", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error for %q, expected %q, got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestFunctionStatements(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedName   string
+		expectedParams []string
+		expectedString string
+	}{
+		{"fn add(x, y) { x + y; }", "add", []string{"x", "y"}, "fn add(x, y) (x + y)"},
+		{"fn noop() {};", "noop", []string{}, "fn noop() "},
+		{"fn fact(n) { n * fact(n - 1) }", "fact", []string{"n"}, "fn fact(n) (n * fact((n - 1)))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.FunctionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.FunctionStatement. got=%T", program.Statements[0])
+		}
+
+		if stmt.Name.Value != tt.expectedName || stmt.Function.Name != tt.expectedName {
+			t.Errorf("wrong name, expected %q, got %q / %q", tt.expectedName, stmt.Name.Value, stmt.Function.Name)
+		}
+
+		if len(stmt.Function.Parameters) != len(tt.expectedParams) {
+			t.Fatalf("wrong number of parameters, expected %d, got %d", len(tt.expectedParams), len(stmt.Function.Parameters))
+		}
+
+		for i, param := range tt.expectedParams {
+			testLiteralExpression(t, stmt.Function.Parameters[i], param)
+		}
+
+		if stmt.String() != tt.expectedString {
+			t.Errorf("wrong String(), expected %q, got %q", tt.expectedString, stmt.String())
+		}
+	}
+}
+
+func TestFunctionStatementVersusLiteral(t *testing.T) {
+	// without a name fn(...) is still a function literal expression
+	input := "fn(x) { x }(1); fn named(x) { x }"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+
+	if _, ok := program.Statements[0].(*ast.ExpressionStatement); !ok {
+		t.Errorf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	if _, ok := program.Statements[1].(*ast.FunctionStatement); !ok {
+		t.Errorf("program.Statements[1] is not ast.FunctionStatement. got=%T", program.Statements[1])
+	}
+}
+
+func TestFunctionStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn add x, y { x + y }", "1:8: expected next token to be (, got IDENT instead"},
+		{"fn add(x, y) x + y", "1:14: expected next token to be {, got IDENT instead"},
+		{"fn add(1) { 1 }", "1:8: expected next token to be IDENT, got INT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+
+		if len(errors) == 0 {
+			t.Errorf("expected errors for %q, got none", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error for %q, expected %q, got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestMethodCallsOnExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3].map(f)", "[1, 2, 3].map(f)"},
+		{`person["tags"].first()`, "(person[tags]).first()"},
+		{"arr.map(f).slice(1)", "arr.map(f).slice(1)"},
+		{"get().pop()", "get().pop()"},
+		{"person.name", "(person.name)"},
+		{"person.address.city", "((person.address).city)"},
+		{"person.tags.first()", "(person.tags).first()"},
+		{"person.tags[0]", "((person.tags)[0])"},
+		{"-arr.len()", "(-arr.len())"},
+		{"a.b + c.d()", "((a.b) + c.d())"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong String() for %q, expected %q, got %q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestPropertyExpression(t *testing.T) {
+	input := "person.name"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.PropertyExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.PropertyExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Left, "person") {
+		return
+	}
+
+	if exp.Property.Value != "name" {
+		t.Errorf("wrong property, expected %q, got %q", "name", exp.Property.Value)
+	}
+}
+
+func TestTryStatements(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParam  string
+		hasCatch       bool
+		hasFinally     bool
+		expectedString string
+	}{
+		{"try { risky() } catch (e) { e }", "e", true, false, "try {risky()} catch(e) {e}"},
+		{"try { risky() } catch { 1 }", "", true, false, "try {risky()} catch {1}"},
+		{"try { risky() } finally { cleanup() }", "", false, true, "try {risky()} finally {cleanup()}"},
+		{"try { risky() } catch (err) { 1 } finally { 2 };", "err", true, true, "try {risky()} catch(err) {1} finally {2}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.TryStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.TryStatement. got=%T", program.Statements[0])
+		}
+
+		param := ""
+		if stmt.CatchParam != nil {
+			param = stmt.CatchParam.Value
+		}
+
+		if param != tt.expectedParam {
+			t.Errorf("wrong catch parameter, expected %q, got %q", tt.expectedParam, param)
+		}
+
+		if (stmt.Catch != nil) != tt.hasCatch {
+			t.Errorf("wrong catch block for %q, expected it to exist: %t", tt.input, tt.hasCatch)
+		}
+
+		if (stmt.Finally != nil) != tt.hasFinally {
+			t.Errorf("wrong finally block for %q, expected it to exist: %t", tt.input, tt.hasFinally)
+		}
+
+		if stmt.String() != tt.expectedString {
+			t.Errorf("wrong String(), expected %q, got %q", tt.expectedString, stmt.String())
+		}
+	}
+}
+
+func TestThrowStatement(t *testing.T) {
+	input := `throw {"message": "bad"};`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.ThrowStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ThrowStatement. got=%T", program.Statements[0])
+	}
+
+	if _, ok := stmt.Value.(*ast.HashLiteral); !ok {
+		t.Errorf("stmt.Value is not ast.HashLiteral. got=%T", stmt.Value)
+	}
+
+	if stmt.String() != "throw {message:bad};" {
+		t.Errorf("wrong String(), got %q", stmt.String())
+	}
+}
+
+func TestTryStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { 1 }", "1:1: try without catch or finally"},
+		{"try 1 catch { 2 }", "1:5: expected next token to be {, got INT instead"},
+		{"try { 1 } catch (1) { 2 }", "1:18: expected next token to be IDENT, got INT instead"},
+		{"try { 1 } catch (e { 2 }", "1:20: expected next token to be ), got { instead"},
+		{"try { 1 } finally 2", "1:19: expected next token to be {, got INT instead"},
+		{"throw;", "1:6: no prefix parse function for ; found"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+
+		if len(errors) == 0 {
+			t.Errorf("expected errors for %q, got none", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error for %q, expected %q, got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestNestingLimit(t *testing.T) {
+	tooDeep := []string{
+		strings.Repeat("(", 1500) + "1" + strings.Repeat(")", 1500),
+		strings.Repeat("-", 1500) + "1",
+		"1" + strings.Repeat(" + 1", 1500),
+		strings.Repeat("if (true) { ", 1500) + "1" + strings.Repeat(" }", 1500),
+		strings.Repeat("fn f() { ", 1500) + "1" + strings.Repeat(" }", 1500) + "; 2",
+	}
+
+	for _, input := range tooDeep {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		nestingErrors := 0
+		for _, diag := range p.Diagnostics() {
+			if diag.Code == diagnostic.TOO_DEEPLY_NESTED {
+				nestingErrors++
+			}
+		}
+
+		if nestingErrors != 1 {
+			t.Errorf("expected 1 nesting error for %.20q..., got %d (%v)", input, nestingErrors, p.Errors())
+		}
+	}
+
+	// the limit is way past anything a real program needs
+	input := strings.Repeat("(", 400) + "1" + strings.Repeat(")", 400)
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+	checkParserErrors(t, p)
+}
+
+/**
+go test ./parser -fuzz FuzzParse
+
+Whatever the input is, the parser should report diagnostics for it instead of panicking.
+Programs without errors have to be printable as well, the REPL relies on String().
+(programs with errors can be missing pieces, nothing uses them)
+**/
+func FuzzParse(f *testing.F) {
+	seeds := []string{
+		"let x = 5 * (2 + 3);",
+		"fn add(x, y = 2, ...rest) { return x + y; }; add(1, y: 3)",
+		"if (x > 1) { x } else if (x < 0) { -x } else { 0 }",
+		"match (x) { 1, 2 => \"small\", _ if x > 10 => \"big\", _ => \"other\" }",
+		"for (let i = 0; i < 10; i = i + 1) { if (i == 2) { continue; } }",
+		"for (x in range(3)) { break; }",
+		"while (true) { break }",
+		"try { throw {\"message\": \"oops\"} } catch (e) { e.message } finally { 1 }",
+		"let h = {\"a\": [1, 2.5, `raw`]}; h[\"a\"][0] = h.a.first()",
+		"\"${1 + \"${2}\"}\"",
+		"let x = ;; fn ( { [ \"unterminated",
+		"/* unterminated comment",
+	}
+
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			_ = program.String()
+		}
+	})
+}
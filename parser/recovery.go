@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"boar/ast"
+	"boar/diagnostic"
+	"boar/token"
+)
+
+// Tokens that can only start a new statement, a safe place to resume parsing after an error.
+var statementKeywords = map[token.TokenType]bool{
+	token.LET:      true,
+	token.CONST:    true,
+	token.FOR:      true,
+	token.WHILE:    true,
+	token.BREAK:    true,
+	token.CONTINUE: true,
+	token.RETURN:   true,
+	token.TRY:      true,
+	token.THROW:    true,
+}
+
+/**
+Parses the current statement.
+If the statement contains a syntax error the parser skips ahead to the start of the next statement,
+so a single call to ParseProgram() can report every error in the input instead of only the first one.
+**/
+func (p *Parser) parseStatementWithRecovery() ast.Statement {
+	// the nesting level the statement starts at
+	braceDepth, groupDepth := p.braceDepth, p.groupDepth
+	if p.curTokenIs(token.LBRACE) {
+		braceDepth--
+	}
+	if p.curTokenIs(token.LPAREN) || p.curTokenIs(token.LBRACKET) {
+		groupDepth--
+	}
+
+	errorsBefore := p.errorCount()
+
+	stmt := p.parseStatement()
+
+	if p.errorCount() > errorsBefore {
+		p.synchronize(braceDepth, groupDepth)
+	}
+
+	return stmt
+}
+
+/**
+Skips tokens until we reach the end of the broken statement (panic-mode recovery).
+
+We stop when, at the nesting level the statement started at:
+- the current token is a ';'
+- the current token is a '}' closing a block that belonged to the statement (if / fn / for bodies)
+- the next token starts a new statement (let, const, fn name, for, return), closes the enclosing block '}' or is EOF
+
+Keywords that start a statement are trusted even inside unclosed parentheses, since they can't appear there
+(other than the 'let' in a for loop header). That keeps a missing ')' from swallowing the rest of the block.
+
+The caller then moves onto the next token like it would after any other statement.
+**/
+func (p *Parser) synchronize(braceDepth, groupDepth int) {
+	for !p.curTokenIs(token.EOF) {
+		if p.braceDepth <= braceDepth {
+			if p.groupDepth <= groupDepth {
+				if p.curTokenIs(token.SEMICOLON) {
+					return
+				}
+
+				// a block just closed, unless the statement keeps going (} else {, }; or fn() {}(x)) we're done
+				if p.curTokenIs(token.RBRACE) && !p.peekTokenIs(token.ELSE) && !p.peekTokenIs(token.SEMICOLON) && p.infixParseFns[p.peekToken.Type] == nil {
+					return
+				}
+			}
+
+			forLoopHeader := p.curTokenIs(token.LPAREN) && p.peekTokenIs(token.LET)
+
+			if p.peekStartsStatement() && !forLoopHeader {
+				return
+			}
+
+			if p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+				return
+			}
+		}
+
+		p.nextToken()
+	}
+}
+
+// Whether the next token can only begin a statement, fn counts when it declares a named function (fn name() {})
+// since anonymous functions show up in the middle of expressions
+func (p *Parser) peekStartsStatement() bool {
+	if p.peekTokenIs(token.FUNCTION) {
+		return p.peekAhead(0).Type == token.IDENT
+	}
+
+	return statementKeywords[p.peekToken.Type]
+}
+
+// Number of error diagnostics reported so far
+func (p *Parser) errorCount() int {
+	count := 0
+
+	for _, d := range p.diagnostics {
+		if d.Severity == diagnostic.ERROR {
+			count++
+		}
+	}
+
+	return count
+}
+
+/**
+Dev Notes:
+
+Error recovery:
+- Without recovery, a parser that hits an unexpected token either stops or keeps going from wherever it happens to be,
+  reporting a cascade of errors that are just side effects of the first one.
+- Panic-mode recovery is the classic compromise: once a statement is known to be broken, skip tokens
+  until we reach a point where it's safe to start parsing again (a synchronization point).
+- Our synchronization points are statement boundaries: ';', the end of a block '}' and keywords that can only begin a statement.
+- braceDepth / groupDepth let us ignore boundaries that are nested deeper than the broken statement
+  (e.g. a ';' inside a function body or inside a for loop header)
+**/
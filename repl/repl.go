@@ -5,6 +5,7 @@ import (
 	"boar/lexer"
 	"boar/object"
 	"boar/parser"
+	"boar/resolver"
 	"boar/setuphelpers"
 	"fmt"
 	"os"
@@ -77,6 +78,7 @@ func readInput(line string) {
 func completer(t prompt.Document) []prompt.Suggest {
 	s := []prompt.Suggest{
 		{Text: "let", Description: "declare a statement"},
+		{Text: "const", Description: "declare a constant"},
 		{Text: "puts", Description: "print a value"},
 		{Text: "fn", Description: "declare a function literal"},
 		{Text: "if", Description: "declare a conditional statement"},
@@ -104,14 +106,6 @@ func printInterpreterPrompt() {
 	fmt.Printf("Hello %s, (type '%s' to exit)\n", userName, terminator)
 }
 
-func printParserErrors(errors []string) {
-	fmt.Print("\n" + setuphelpers.BOAR + " Error!:\n")
-	for _, msg := range errors {
-		fmt.Print("> " + msg + "\n\n")
-		fmt.Println()
-	}
-}
-
 func evaluate(line string) {
 	CODE_BUFFER = append(CODE_BUFFER, line)
 
@@ -132,7 +126,13 @@ func evaluate(line string) {
 	program := p.ParseProgram()
 
 	if len(p.Errors()) != 0 {
-		printParserErrors(p.Errors())
+		setuphelpers.PrintParserErrors(os.Stdout, code, p.Diagnostics())
+		return
+	}
+
+	// undefined variables, the globals from the previous lines are in ENV
+	if diagnostics := resolver.Resolve(program, ENV); len(diagnostics) != 0 {
+		setuphelpers.PrintParserErrors(os.Stdout, code, diagnostics)
 		return
 	}
 
@@ -140,7 +140,7 @@ func evaluate(line string) {
 	evaluated := evaluator.Eval(program, ENV)
 	if evaluated != nil {
 		// apply syntax highlighting
-		str := setuphelpers.ApplyColorToText(evaluated.Inspect())
+		str := setuphelpers.ApplyColorToText(setuphelpers.FormatResult(evaluated))
 		fmt.Println(str)
 	}
 }
@@ -149,8 +149,9 @@ func emptyCodeBuffer() {
 	CODE_BUFFER = make([]string, 0)
 }
 
+// Keep the line breaks so a '//' comment only covers its own line (and diagnostics point at the right line)
 func formatLine(lines []string) string {
-	return strings.Join(lines, " ")
+	return strings.Join(lines, "\n")
 }
 
 func getFinalChar(line string) rune {
@@ -0,0 +1,417 @@
+/**
+Package resolver works out where the variable behind every identifier of a program lives, before the program runs.
+
+- Local variables (parameters, lets inside of functions and blocks, catch parameters, loop variables) get a slot
+in their scope, identifiers using them get the slot and how many scopes up it is (see ast.Binding).
+The evaluator reads them with an index instead of looking the name up in a map in every scope.
+- Everything else (declared at the top level, outside of any block) is a global, looked up by name in the global environment.
+- Identifiers that aren't declared anywhere are reported as errors, the program doesn't run.
+
+A local variable exists from its let to the end of its block:
+- code before the let doesn't see it, it gets the variable of an outer scope (or a "used before its declaration" error if there's none)
+- functions defined in the block see it no matter where they're defined, their bodies are resolved once the block is complete.
+Calling one before the let ran is a runtime error: fn f() { let h = fn() { x }; h(); let x = 1 }
+
+Every block has its own scope: function bodies, if / else, loops, match arms, try / catch / finally and bare { ... } blocks.
+Blocks that don't declare anything don't get one, the evaluator doesn't have to create an environment for them.
+**/
+package resolver
+
+import (
+	"boar/ast"
+	"boar/diagnostic"
+	"boar/object"
+	"sort"
+)
+
+// The local variables of a function call, a block or an iteration of a loop
+type scope struct {
+	slots map[string]int
+	size  int
+}
+
+/**
+Function bodies get resolved once the scope they're defined in is complete (see resolveFunctions),
+that way they can use variables declared after them:
+
+	fn even(n) { if (n == 0) { true } else { odd(n - 1) } }
+	fn odd(n) { if (n == 0) { false } else { even(n - 1) } }
+**/
+type pendingFunction struct {
+	fn     *ast.FunctionLiteral
+	scopes []*scope // the scopes around the function when it was defined
+}
+
+type globalUse struct {
+	ident      *ast.Identifier
+	assignment bool
+	scopes     []*scope // the local scopes around it, to tell a variable used before its let from an undefined one
+}
+
+type resolver struct {
+	scopes  []*scope
+	pending []pendingFunction
+	globals map[string]bool // globals declared by the program
+	uses    []globalUse     // identifiers that aren't locals, checked once the whole program was seen
+}
+
+/**
+Binds every identifier in program to its variable and returns the undefined ones as diagnostics.
+
+env holds the globals that exist before the program runs (builtins, earlier lines in the REPL, etc), it can be nil.
+The program is only marked as resolved when there were no errors.
+**/
+func Resolve(program *ast.Program, env *object.Environment) []*diagnostic.Diagnostic {
+	r := &resolver{globals: map[string]bool{}}
+
+	r.resolveStatements(program.Statements)
+	r.resolveFunctions()
+
+	diagnostics := []*diagnostic.Diagnostic{}
+
+	for _, use := range r.uses {
+		name := use.ident.Value
+		if r.globals[name] || (env != nil && env.HasGlobal(name)) {
+			continue
+		}
+
+		var d *diagnostic.Diagnostic
+		if declaredLater(use) {
+			d = diagnostic.NewError(diagnostic.UNDEFINED_VARIABLE, use.ident.Token, "%s used before its declaration", name).
+				WithHint("%s is declared further down in the same block, move the declaration up", name)
+		} else {
+			d = diagnostic.NewError(diagnostic.UNDEFINED_VARIABLE, use.ident.Token, "identifier not found: %s", name)
+			if use.assignment {
+				d.WithHint("assignments don't declare variables, use let %s = ... to declare it", name)
+			}
+		}
+		diagnostics = append(diagnostics, d)
+	}
+
+	// function bodies are resolved out of order, report the errors in the order they appear in the code
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Span.Start.Offset < diagnostics[j].Span.Start.Offset
+	})
+
+	program.Resolved = len(diagnostics) == 0
+
+	return diagnostics
+}
+
+// Whether one of the scopes around an undefined identifier declares it after the identifier was used: fn f() { x; let x = 1 }
+func declaredLater(use globalUse) bool {
+	for _, s := range use.scopes {
+		if _, ok := s.slots[use.ident.Value]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *resolver) resolveStatements(stmts []ast.Statement) {
+	// fn declarations can be used before the line they're on, see evaluator.hoistFunctions
+	for _, statement := range stmts {
+		if decl, ok := statement.(*ast.FunctionStatement); ok {
+			r.declare(decl.Name)
+		}
+	}
+
+	for _, statement := range stmts {
+		r.resolveStatement(statement)
+	}
+}
+
+// A block that uses the scope it's in: function bodies, catch blocks and for-in bodies, their scope was already pushed
+func (r *resolver) resolveBlock(block *ast.BlockStatement) {
+	if block != nil {
+		r.resolveStatements(block.Statements)
+	}
+}
+
+// A block with a scope of its own, if it declares any variables
+func (r *resolver) resolveScopedBlock(block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+
+	if !declaresVariables(block.Statements) {
+		r.resolveStatements(block.Statements)
+		return
+	}
+
+	r.push()
+	r.resolveStatements(block.Statements)
+	block.Locals = r.pop()
+}
+
+// let, const and fn name() { ... } right inside of the block (not in a nested one)
+func declaresVariables(stmts []ast.Statement) bool {
+	for _, statement := range stmts {
+		switch statement.(type) {
+		case *ast.LetStatement, *ast.FunctionStatement:
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *resolver) resolveStatement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.ExpressionStatement:
+		r.resolveExpression(stmt.Expression)
+
+	case *ast.LetStatement:
+		// let x = x + 1 uses the x from before
+		r.resolveExpression(stmt.Value)
+		r.declare(stmt.Name)
+
+	case *ast.ReturnStatement:
+		r.resolveExpression(stmt.ReturnValue)
+
+	case *ast.ThrowStatement:
+		r.resolveExpression(stmt.Value)
+
+	case *ast.BlockStatement:
+		r.resolveScopedBlock(stmt)
+
+	// the name was declared along with the rest of the block
+	case *ast.FunctionStatement:
+		r.later(stmt.Function)
+
+	case *ast.TryStatement:
+		r.resolveScopedBlock(stmt.Block)
+
+		if stmt.Catch != nil {
+			r.push()
+			if stmt.CatchParam != nil {
+				r.declare(stmt.CatchParam)
+			}
+			r.resolveBlock(stmt.Catch)
+			stmt.CatchLocals = r.pop()
+		}
+
+		r.resolveScopedBlock(stmt.Finally)
+
+	case *ast.WhileStatement:
+		r.resolveExpression(stmt.Condition)
+		r.resolveScopedBlock(stmt.Body)
+
+	// the counter belongs to the loop, the body is a block inside of it
+	case *ast.ForLoopStatement:
+		r.push()
+		if stmt.CounterVar != nil {
+			r.resolveStatement(stmt.CounterVar)
+		}
+		r.resolveExpression(stmt.LoopCondition)
+		// the update always goes to the counter, see evaluator.applyForLoop
+		if stmt.CounterUpdate != nil {
+			r.resolveExpression(stmt.CounterUpdate.Value)
+		}
+		r.resolveScopedBlock(stmt.LoopBlock)
+		stmt.Locals = r.pop()
+
+	case *ast.ForInStatement:
+		r.resolveExpression(stmt.Iterable)
+
+		r.push()
+		for _, variable := range stmt.Variables {
+			r.declare(variable)
+		}
+		r.resolveBlock(stmt.Body)
+		stmt.Locals = r.pop()
+	}
+}
+
+func (r *resolver) resolveExpression(exp ast.Expression) {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		r.use(exp, false)
+
+	case *ast.AssignmentExpression:
+		r.resolveExpression(exp.Value)
+		r.use(exp.Name, true)
+
+	case *ast.PrefixExpression:
+		r.resolveExpression(exp.Right)
+
+	case *ast.InfixExpression:
+		r.resolveExpression(exp.Left)
+		r.resolveExpression(exp.Right)
+
+	case *ast.LogicalExpression:
+		r.resolveExpression(exp.Left)
+		r.resolveExpression(exp.Right)
+
+	case *ast.IfExpression:
+		r.resolveExpression(exp.Condition)
+		r.resolveScopedBlock(exp.Consequence)
+		r.resolveScopedBlock(exp.Alternative)
+
+	case *ast.MatchExpression:
+		r.resolveExpression(exp.Subject)
+
+		for _, arm := range exp.Arms {
+			for _, pattern := range arm.Patterns {
+				if !ast.IsWildcard(pattern) {
+					r.resolveExpression(pattern)
+				}
+			}
+			r.resolveExpression(arm.Guard)
+			r.resolveScopedBlock(arm.Body)
+		}
+
+	case *ast.FunctionLiteral:
+		r.later(exp)
+
+	case *ast.CallExpression:
+		r.resolveExpression(exp.Function)
+		r.resolveExpressions(exp.Arguments)
+
+		// the names of named arguments are parameter names, not variables
+		for _, arg := range exp.NamedArguments {
+			r.resolveExpression(arg.Value)
+		}
+
+	case *ast.InternalFunctionCall:
+		r.resolveExpression(exp.Caller)
+		if exp.FunctionIdentifier != nil {
+			r.use(exp.FunctionIdentifier, false)
+		}
+		r.resolveExpressions(exp.Arguments)
+
+	// person.name, name is a key of the hash
+	case *ast.PropertyExpression:
+		r.resolveExpression(exp.Left)
+
+	case *ast.InterpolatedString:
+		r.resolveExpressions(exp.Parts)
+
+	case *ast.ArrayLiteral:
+		r.resolveExpressions(exp.Elements)
+
+	case *ast.HashLiteral:
+		for _, pair := range exp.Pairs {
+			r.resolveExpression(pair.Key)
+			r.resolveExpression(pair.Value)
+		}
+
+	case *ast.IndexExpression:
+		r.resolveExpression(exp.Left)
+		r.resolveExpression(exp.Index)
+
+	case *ast.IndexAssignment:
+		r.resolveExpression(exp.Left)
+		r.resolveExpression(exp.Index)
+		r.resolveExpression(exp.Value)
+	}
+}
+
+func (r *resolver) resolveExpressions(exps []ast.Expression) {
+	for _, exp := range exps {
+		r.resolveExpression(exp)
+	}
+}
+
+// Remembers fn along with the scopes around it, see pendingFunction
+func (r *resolver) later(fn *ast.FunctionLiteral) {
+	if fn == nil {
+		return
+	}
+
+	scopes := make([]*scope, len(r.scopes))
+	copy(scopes, r.scopes)
+
+	r.pending = append(r.pending, pendingFunction{fn: fn, scopes: scopes})
+}
+
+// Resolves the pending functions, including the ones defined inside of them
+func (r *resolver) resolveFunctions() {
+	outer := r.scopes
+
+	for len(r.pending) > 0 {
+		next := r.pending[0]
+		r.pending = r.pending[1:]
+
+		r.scopes = next.scopes
+		r.resolveFunction(next.fn)
+	}
+
+	r.scopes = outer
+}
+
+/**
+The parameters are declared in order, a default value can use the parameters before it: fn(x, y = x * 2).
+Functions defined in the body are resolved before leaving it, the body's scope is complete by then.
+**/
+func (r *resolver) resolveFunction(fn *ast.FunctionLiteral) {
+	outerPending := r.pending
+	r.pending = nil
+
+	r.push()
+
+	for _, param := range fn.Parameters {
+		r.resolveExpression(fn.Defaults[param.Value])
+		r.declare(param)
+	}
+
+	if fn.Rest != nil {
+		r.declare(fn.Rest)
+	}
+
+	r.resolveBlock(fn.Body)
+	r.resolveFunctions()
+
+	fn.Locals = r.pop()
+
+	r.pending = outerPending
+}
+
+func (r *resolver) push() {
+	r.scopes = append(r.scopes, &scope{slots: map[string]int{}})
+}
+
+// Leaves the current scope, returns how many slots it needs
+func (r *resolver) pop() int {
+	current := r.scopes[len(r.scopes)-1]
+	r.scopes = r.scopes[:len(r.scopes)-1]
+
+	return current.size
+}
+
+// let x, fn x(), parameters, etc. Declaring the same name twice in a scope reuses its slot
+func (r *resolver) declare(ident *ast.Identifier) {
+	if len(r.scopes) == 0 {
+		r.globals[ident.Value] = true
+		ident.Binding = ast.Binding{Kind: ast.GLOBAL}
+		return
+	}
+
+	current := r.scopes[len(r.scopes)-1]
+
+	slot, ok := current.slots[ident.Value]
+	if !ok {
+		slot = current.size
+		current.slots[ident.Value] = slot
+		current.size++
+	}
+
+	ident.Binding = ast.Binding{Kind: ast.LOCAL, Depth: 0, Slot: slot}
+}
+
+// The innermost local variable with the identifier's name, a global if there isn't one
+func (r *resolver) use(ident *ast.Identifier, assignment bool) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if slot, ok := r.scopes[i].slots[ident.Value]; ok {
+			ident.Binding = ast.Binding{Kind: ast.LOCAL, Depth: len(r.scopes) - 1 - i, Slot: slot}
+			return
+		}
+	}
+
+	ident.Binding = ast.Binding{Kind: ast.GLOBAL}
+
+	scopes := make([]*scope, len(r.scopes))
+	copy(scopes, r.scopes)
+	r.uses = append(r.uses, globalUse{ident: ident, assignment: assignment, scopes: scopes})
+}
@@ -0,0 +1,220 @@
+package resolver
+
+import (
+	"boar/ast"
+	"boar/diagnostic"
+	"boar/lexer"
+	"boar/object"
+	"boar/parser"
+	"testing"
+)
+
+func TestBindings(t *testing.T) {
+	program := parse(t, "fn f(a, b) { let c = a; fn() { b + c } }")
+
+	if diagnostics := Resolve(program, nil); len(diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+
+	if !program.Resolved {
+		t.Fatalf("expected the program to be marked as resolved")
+	}
+
+	decl := program.Statements[0].(*ast.FunctionStatement)
+	testBinding(t, decl.Name, ast.Binding{Kind: ast.GLOBAL})
+
+	fn := decl.Function
+	if fn.Locals != 3 {
+		t.Errorf("expected f to have 3 locals, got %d", fn.Locals)
+	}
+	testBinding(t, fn.Parameters[0], ast.Binding{Kind: ast.LOCAL, Depth: 0, Slot: 0})
+	testBinding(t, fn.Parameters[1], ast.Binding{Kind: ast.LOCAL, Depth: 0, Slot: 1})
+
+	let := fn.Body.Statements[0].(*ast.LetStatement)
+	testBinding(t, let.Name, ast.Binding{Kind: ast.LOCAL, Depth: 0, Slot: 2})
+	testBinding(t, let.Value.(*ast.Identifier), ast.Binding{Kind: ast.LOCAL, Depth: 0, Slot: 0})
+
+	inner := fn.Body.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if inner.Locals != 0 {
+		t.Errorf("expected the inner function to have no locals, got %d", inner.Locals)
+	}
+
+	sum := inner.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.InfixExpression)
+	testBinding(t, sum.Left.(*ast.Identifier), ast.Binding{Kind: ast.LOCAL, Depth: 1, Slot: 1})
+	testBinding(t, sum.Right.(*ast.Identifier), ast.Binding{Kind: ast.LOCAL, Depth: 1, Slot: 2})
+}
+
+func TestScopes(t *testing.T) {
+	// redeclaring a variable reuses its slot
+	program := parse(t, "fn f(x) { let y = 1; let y = 2; let x = y }")
+	Resolve(program, nil)
+
+	fn := program.Statements[0].(*ast.FunctionStatement).Function
+	if fn.Locals != 2 {
+		t.Errorf("expected f to have 2 locals, got %d", fn.Locals)
+	}
+
+	// the loop variables and the catch parameter get their own scope
+	program = parse(t, "fn f() { for (k, v in {}) { let z = k }; try { 1 } catch (e) { e } }")
+	Resolve(program, nil)
+
+	fn = program.Statements[0].(*ast.FunctionStatement).Function
+	if fn.Locals != 0 {
+		t.Errorf("expected f to have no locals, got %d", fn.Locals)
+	}
+
+	forIn := fn.Body.Statements[0].(*ast.ForInStatement)
+	if forIn.Locals != 3 {
+		t.Errorf("expected the for-in loop to have 3 locals, got %d", forIn.Locals)
+	}
+
+	let := forIn.Body.Statements[0].(*ast.LetStatement)
+	testBinding(t, let.Value.(*ast.Identifier), ast.Binding{Kind: ast.LOCAL, Depth: 0, Slot: 0})
+
+	try := fn.Body.Statements[1].(*ast.TryStatement)
+	if try.CatchLocals != 1 {
+		t.Errorf("expected the catch block to have 1 local, got %d", try.CatchLocals)
+	}
+
+	// blocks only get a scope when they declare something
+	program = parse(t, "let g = 1; if (true) { let h = g; h } else { g }")
+	Resolve(program, nil)
+
+	ifExp := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.IfExpression)
+	if ifExp.Consequence.Locals != 1 || ifExp.Alternative.Locals != 0 {
+		t.Errorf("expected the blocks to have 1 and 0 locals, got %d and %d", ifExp.Consequence.Locals, ifExp.Alternative.Locals)
+	}
+
+	let = ifExp.Consequence.Statements[0].(*ast.LetStatement)
+	testBinding(t, let.Name, ast.Binding{Kind: ast.LOCAL, Depth: 0, Slot: 0})
+	testBinding(t, let.Value.(*ast.Identifier), ast.Binding{Kind: ast.GLOBAL})
+
+	// the counter of a for loop is in the loop's scope, the body is a scope inside of it
+	program = parse(t, "for (let i = 0; i < 3; i = i + 1) { let j = i }")
+	Resolve(program, nil)
+
+	forLoop := program.Statements[0].(*ast.ForLoopStatement)
+	if forLoop.Locals != 1 || forLoop.LoopBlock.Locals != 1 {
+		t.Errorf("expected the loop and its body to have 1 local each, got %d and %d", forLoop.Locals, forLoop.LoopBlock.Locals)
+	}
+
+	testBinding(t, forLoop.CounterVar.Name, ast.Binding{Kind: ast.LOCAL, Depth: 0, Slot: 0})
+	let = forLoop.LoopBlock.Statements[0].(*ast.LetStatement)
+	testBinding(t, let.Value.(*ast.Identifier), ast.Binding{Kind: ast.LOCAL, Depth: 1, Slot: 0})
+}
+
+func TestUndefinedVariables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1; x", []string{}},
+		{"x", []string{"1:1: identifier not found: x"}},
+		{"len(puts)", []string{}},
+		// the value is resolved before the variable gets declared
+		{"fn f() { let x = x }", []string{"1:18: x used before its declaration"}},
+		{"fn f() { if (true) { y = 2 }; let y = 1 }", []string{"1:22: y used before its declaration"}},
+		// the outer variable until the let
+		{"let z = 1; fn f() { let a = z; let z = 2 }", []string{}},
+		// functions can use what's declared after them
+		{"fn f() { g() }; fn g() { y }; let y = 1", []string{}},
+		{"let f = fn() { fn() { z } }", []string{"1:23: identifier not found: z"}},
+		{"fn f(a, b = a) { a + b + c }", []string{"1:26: identifier not found: c"}},
+		{"fn f(...rest) { rest }; rest", []string{"1:25: identifier not found: rest"}},
+		{"fn f() { let n = 1; fn() { n } }; n", []string{"1:35: identifier not found: n"}},
+		{"for (x in [1]) { x }; x", []string{"1:23: identifier not found: x"}},
+		{"try { 1 } catch (e) { e }; e", []string{"1:28: identifier not found: e"}},
+		{"for (let i = 0; i < 3; i = i + 1) { i }", []string{}},
+		// blocks have their own scope
+		{"while (true) { let w = 1 }; w", []string{"1:29: identifier not found: w"}},
+		{"if (true) { let a = 1 } else { a }", []string{"1:32: identifier not found: a"}},
+		{"for (let i = 0; i < 3; i = i + 1) { }; i", []string{"1:40: identifier not found: i"}},
+		{"{ let b = 1 }; b", []string{"1:16: identifier not found: b"}},
+		// named arguments and properties aren't variables
+		{"fn f(y) { y }; f(y: 1)", []string{}},
+		{"let h = {}; h.name", []string{}},
+		{"[1].nope()", []string{"1:5: identifier not found: nope"}},
+		{"match (1) { _ if a => b }", []string{"1:18: identifier not found: a", "1:23: identifier not found: b"}},
+		{"\"${a}\"; {b: c}; [d][e]", []string{"1:4: identifier not found: a", "1:10: identifier not found: b", "1:13: identifier not found: c", "1:18: identifier not found: d", "1:21: identifier not found: e"}},
+		// sorted by position, even though function bodies are resolved last
+		{"fn f() { b }; a", []string{"1:10: identifier not found: b", "1:15: identifier not found: a"}},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("len", &object.Builtin{})
+		env.Set("puts", &object.Builtin{})
+
+		program := parse(t, tt.input)
+		diagnostics := Resolve(program, env)
+
+		if len(diagnostics) != len(tt.expected) {
+			t.Errorf("wrong number of diagnostics for %q, expected %d, got %d: %v", tt.input, len(tt.expected), len(diagnostics), diagnostics)
+			continue
+		}
+
+		for i, d := range diagnostics {
+			if d.Code != diagnostic.UNDEFINED_VARIABLE {
+				t.Errorf("wrong code for %q, got %s", tt.input, d.Code)
+			}
+			if d.String() != tt.expected[i] {
+				t.Errorf("wrong diagnostic for %q, expected %q, got %q", tt.input, tt.expected[i], d.String())
+			}
+		}
+
+		if program.Resolved != (len(diagnostics) == 0) {
+			t.Errorf("wrong Resolved for %q, got %t", tt.input, program.Resolved)
+		}
+	}
+}
+
+func TestUndefinedAssignment(t *testing.T) {
+	diagnostics := Resolve(parse(t, "count = 1"), nil)
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(diagnostics))
+	}
+
+	expectedHint := "assignments don't declare variables, use let count = ... to declare it"
+	if len(diagnostics[0].Hints) != 1 || diagnostics[0].Hints[0] != expectedHint {
+		t.Errorf("wrong hints, got %v", diagnostics[0].Hints)
+	}
+}
+
+func TestUsedBeforeDeclaration(t *testing.T) {
+	diagnostics := Resolve(parse(t, "fn f() { let sum = total; let total = 0 }"), nil)
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("expected 1 diagnostic, got %d", len(diagnostics))
+	}
+
+	if diagnostics[0].Message != "total used before its declaration" {
+		t.Errorf("wrong message, got %q", diagnostics[0].Message)
+	}
+
+	expectedHint := "total is declared further down in the same block, move the declaration up"
+	if len(diagnostics[0].Hints) != 1 || diagnostics[0].Hints[0] != expectedHint {
+		t.Errorf("wrong hints, got %v", diagnostics[0].Hints)
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	return program
+}
+
+func testBinding(t *testing.T, ident *ast.Identifier, expected ast.Binding) {
+	t.Helper()
+
+	if ident.Binding != expected {
+		t.Errorf("wrong binding for %s, expected %+v, got %+v", ident.Value, expected, ident.Binding)
+	}
+}
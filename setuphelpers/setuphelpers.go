@@ -1,6 +1,7 @@
 package setuphelpers
 
 import (
+	"boar/diagnostic"
 	"boar/evaluator"
 	"boar/object"
 	"bytes"
@@ -18,11 +19,20 @@ func LoadBuiltInMethods(env *object.Environment) {
 	}
 }
 
-func PrintParserErrors(out io.Writer, errors []string) {
+// Prints every parser diagnostic along with the source line it points at
+func PrintParserErrors(out io.Writer, source string, diagnostics []*diagnostic.Diagnostic) {
 	io.WriteString(out, "\n"+BOAR+" Error!:\n")
-	for _, msg := range errors {
-		io.WriteString(out, "> "+msg+"\n\n")
+	diagnostic.RenderAll(out, source, diagnostics)
+	io.WriteString(out, "\n")
+}
+
+// What gets printed for the result of a program, errors come with their stack trace
+func FormatResult(obj object.Object) string {
+	if err, ok := obj.(*object.Error); ok {
+		return err.StackTrace()
 	}
+
+	return obj.Inspect()
 }
 
 func ApplyColorToText(str string) string {
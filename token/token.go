@@ -1,5 +1,7 @@
 package token
 
+import "fmt"
+
 // Allows us to distinguish between different types of tokens
 type TokenType string
 
@@ -12,8 +14,19 @@ const (
 	// Idenfifiers + literals
 	IDENT  = "IDENT" // add, foobar, x, y, etc.
 	INT    = "INT"   // 123456
+	FLOAT  = "FLOAT" // 1.5, .5, 1e-3
 	STRING = "STRING"
 
+	// Interpolated strings: "Hello ${name}, you have ${count} items" is lexed as
+	// INTERP_START("Hello ") IDENT(name) INTERP_MID(", you have ") IDENT(count) INTERP_END(" items")
+	INTERP_START = "INTERP_START" // text before the first ${
+	INTERP_MID   = "INTERP_MID"   // text between a } and the next ${
+	INTERP_END   = "INTERP_END"   // text after the last }
+
+	// Comments: // line comment, /* block comment */
+	// Only emitted by the lexer when asked to, otherwise they're skipped like whitespace.
+	COMMENT = "COMMENT"
+
 	// Operators
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -21,16 +34,24 @@ const (
 	BANG     = "!"
 	ASTERISK = "*"
 	SLASH    = "/"
-	LT       = "<" // less than
-	GT       = ">" // greater than
+	PERCENT  = "%"  // modulo
+	POWER    = "**" // exponent
+	LT       = "<"  // less than
+	GT       = ">"  // greater than
+	LT_EQ    = "<=" // less than or equal
+	GT_EQ    = ">=" // greater than or equal
 	EQ       = "=="
 	NOT_EQ   = "!="
+	AND      = "&&"
+	OR       = "||"
+	ARROW    = "=>" // separates a match arm's patterns from its body
 
 	// Delimiters
 	COMMA     = ","
 	SEMICOLON = ";"
 	COLON     = ":"
 	DOT       = "."
+	ELLIPSIS  = "..." // rest parameters: fn(first, ...rest)
 
 	//parenthesis + brackets
 	LPAREN   = "("
@@ -43,30 +64,88 @@ const (
 	// Keywords
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	FOR      = "FOR"
+	WHILE    = "WHILE"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	IN       = "IN"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	MATCH    = "MATCH"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	FINALLY  = "FINALLY"
+	THROW    = "THROW"
 )
 
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // where the token starts in the source
+	// right after the token's last character, the literal can be shorter than the source ("a\tb", ${ ... })
+	// the zero value when the token didn't come from the lexer
+	End Position
+}
+
+/**
+Position of a token in the source code.
+
+- Line and Column are 1-based, Offset is the 0-based byte offset into the input.
+- File is empty when the source didn't come from a file (REPL, tests, etc).
+- The zero value is an invalid / unknown position.
+**/
+type Position struct {
+	File   string
+	Line   int
+	Column int
+	Offset int
+}
+
+// Reports whether the position points at an actual location in the source
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// file:line:column, line:column or "-" when the position is unknown
+func (p Position) String() string {
+	if !p.IsValid() {
+		return "-"
+	}
+
+	pos := fmt.Sprintf("%d:%d", p.Line, p.Column)
+
+	if p.File != "" {
+		pos = p.File + ":" + pos
+	}
+
+	return pos
 }
 
 // map these keywords to their token types
 // investigate
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"for":    FOR,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"const":    CONST,
+	"for":      FOR,
+	"while":    WHILE,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"in":       IN,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"match":    MATCH,
+	"try":      TRY,
+	"catch":    CATCH,
+	"finally":  FINALLY,
+	"throw":    THROW,
 }
 
 /**